@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/Kelen/Korner/internal/prompts"
+	wailsruntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// ListActions returns the user's quick actions for the pie menu
+func (a *App) ListActions() []prompts.Action {
+	return prompts.Actions()
+}
+
+// ReloadPrompts reads the templates and actions again after the user edited them
+func (a *App) ReloadPrompts() error {
+	return prompts.Load(prompts.DefaultDir())
+}
+
+// GetPromptsDir returns the directory holding the user's templates and actions.json
+func (a *App) GetPromptsDir() string {
+	if dir := prompts.Dir(); dir != "" {
+		return dir
+	}
+	return prompts.DefaultDir()
+}
+
+// RunAction runs the quick action actionID on the screenshot and streams the
+// answer like QueryLLMStream. Actions that refer to {{.OCRText}} get the
+// screenshot as text, the others send it to the model as an image.
+// {{.Selection}} is the text on the clipboard, read only for actions that
+// opted in with "clipboard": true so copied secrets stay on the machine.
+func (a *App) RunAction(requestID string, threadID string, actionID string, screenshotBase64 string, language string, noCache bool) (string, error) {
+	action, ok := prompts.FindAction(actionID)
+	if !ok {
+		return "", fmt.Errorf("找不到快捷動作: %s", actionID)
+	}
+	log.Printf("[RunAction] Request %s running %s", requestID, action.ID)
+
+	ctx, done := a.beginRequest(requestID)
+	defer done()
+	ctx = withCacheBypass(ctx, noCache)
+
+	if language == "" && a.settings != nil {
+		language = a.settings.Language
+	}
+	data := prompts.NewData(language)
+	if action.UsesSelection() && a.ctx != nil {
+		if text, err := wailsruntime.ClipboardGetText(a.ctx); err == nil {
+			data.Selection = text
+		}
+	}
+
+	if action.UsesOCR() && screenshotBase64 != "" {
+		text, err := a.extractText(ctx, screenshotBase64)
+		if err != nil {
+			err = requestError(ctx, err)
+			a.emitStream(StreamEvent{RequestID: requestID, Done: true, Error: err.Error()})
+			return "", err
+		}
+		data.OCRText = text
+		// The model already gets the text, do not OCR the image a second time
+		screenshotBase64 = ""
+	}
+
+	query, err := action.Render(data)
+	if err != nil {
+		a.emitStream(StreamEvent{RequestID: requestID, Done: true, Error: err.Error()})
+		return "", err
+	}
+
+	resp, err := a.queryLLM(ctx, threadID, query, screenshotBase64, language, func(chunk string) {
+		a.emitStream(StreamEvent{RequestID: requestID, Chunk: chunk})
+	}, func(notice CondenseNotice) {
+		a.emitStream(StreamEvent{RequestID: requestID, Condensed: &notice})
+	})
+	if err != nil {
+		a.emitStream(StreamEvent{RequestID: requestID, Done: true, Error: err.Error()})
+		return "", err
+	}
+
+	a.emitStream(StreamEvent{RequestID: requestID, Text: resp.Text, Reasoning: resp.Reasoning, Done: true})
+	return resp.Text, nil
+}
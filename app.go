@@ -12,13 +12,17 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Kelen/Korner/internal/audio"
+	"github.com/Kelen/Korner/internal/cache"
 	"github.com/Kelen/Korner/internal/history"
 	"github.com/Kelen/Korner/internal/llm"
+	"github.com/Kelen/Korner/internal/metrics"
 	"github.com/Kelen/Korner/internal/ocr"
 	"github.com/Kelen/Korner/internal/platform"
+	"github.com/Kelen/Korner/internal/prompts"
 	wailsruntime "github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
@@ -29,6 +33,11 @@ type App struct {
 	platform platform.Platform
 	history  *history.Manager
 	recorder *audio.Recorder
+	cache    *cache.Cache // OCR results and answers, see responseCache
+	metrics  *metrics.Store
+
+	requestsMu sync.Mutex
+	requests   map[string]*activeRequest // In-flight requests by ID, see CancelRequest
 }
 
 // AppSettings stores user configuration
@@ -39,6 +48,31 @@ type AppSettings struct {
 	FloatingIcon   string `json:"floatingIcon"`
 	Language       string `json:"language"`       // "en" or "zh-TW"
 	OllamaEndpoint string `json:"ollamaEndpoint"` // Ollama server endpoint
+	GeminiEndpoint string `json:"geminiEndpoint"` // Gemini API base URL
+
+	AnthropicEndpoint string `json:"anthropicEndpoint"` // Anthropic API base URL
+	MaxTokens         int    `json:"maxTokens"`         // Answer length limit, 0 uses the provider default
+
+	OpenAIEndpoint string            `json:"openaiEndpoint"` // OpenAI-compatible base URL (OpenAI, LM Studio, vLLM)
+	OpenAIVision   bool              `json:"openaiVision"`   // Send screenshots even if the model is not recognised as a vision model
+	Models         map[string]string `json:"models"`         // Chosen model per provider, empty uses the provider default
+
+	FallbackProviders []string `json:"fallbackProviders"` // Tried in order when the main provider is down
+
+	EnableTools bool `json:"enableTools"` // Let the model call web search, PDF, history and screen tools
+
+	DisableCache  bool `json:"disableCache"`  // Always ask the model and OCR again
+	CacheTTLHours int  `json:"cacheTTLHours"` // How long cached results stay valid, 0 uses 24 hours
+	CacheMaxMB    int  `json:"cacheMaxMB"`    // Size cap of the cache, 0 uses 200 MB
+
+	OCREngine          string `json:"ocrEngine"`          // "ollama", "tesseract" or "http", empty uses ollama
+	TesseractPath      string `json:"tesseractPath"`      // Empty searches PATH and the usual install folders
+	TesseractLanguages string `json:"tesseractLanguages"` // Such as "chi_tra+eng", empty uses that
+	OCRServerURL       string `json:"ocrServerURL"`       // OCR server of the http engine
+
+	SearchProvider string `json:"searchProvider"` // "duckduckgo", "searxng", "brave" or "bing", empty uses duckduckgo
+	SearchURL      string `json:"searchURL"`      // SearXNG instance, or another endpoint for the other providers
+	SearchAPIKey   string `json:"searchAPIKey"`   // Brave or Bing subscription key
 }
 
 // NewApp creates a new App application struct
@@ -47,20 +81,31 @@ func NewApp() *App {
 	if err != nil {
 		log.Printf("Warning: failed to initialize history manager: %v", err)
 	}
+	if err := prompts.Load(prompts.DefaultDir()); err != nil {
+		log.Printf("Warning: failed to load prompt templates: %v", err)
+	}
 
 	app := &App{
 		settings: &AppSettings{
 			APIProvider:    "ollama", // 默認使用 Ollama
 			APIKey:         "dummy-key",
-			APIEndpoint:    "http://210.61.209.139:45014/v1/", // GPT-OSS 端點
+			APIEndpoint:    llm.DefaultGPTOSSEndpoint, // GPT-OSS 端點
 			FloatingIcon:   "🌸",
-			Language:       "zh-TW",                     // 默認語言
-			OllamaEndpoint: "http://127.0.0.1:11434", // Ollama 端點
+			Language:       "zh-TW",                   // 默認語言
+			OllamaEndpoint: ocr.DefaultEndpoint,       // Ollama 端點
+			GeminiEndpoint: llm.DefaultGeminiEndpoint, // Gemini 端點
+
+			AnthropicEndpoint: llm.DefaultAnthropicEndpoint, // Anthropic 端點
+			MaxTokens:         2048,
+
+			OpenAIEndpoint: llm.DefaultOpenAIEndpoint, // OpenAI 相容端點
 		},
 		platform: platform.New(),
 		history:  historyMgr,
 	}
 	app.loadSettings()
+	app.openCache()
+	app.openMetrics()
 	return app
 }
 
@@ -95,7 +140,17 @@ func (a *App) loadSettings() {
 
 // SaveSettings saves settings to file
 func (a *App) SaveSettings(settings AppSettings) error {
+	if a.settings != nil && (a.settings.APIEndpoint != settings.APIEndpoint ||
+		a.settings.OpenAIEndpoint != settings.OpenAIEndpoint ||
+		a.settings.Models["gptoss"] != settings.Models["gptoss"] ||
+		a.settings.Models["openai"] != settings.Models["openai"]) {
+		// Detected model names belong to the old endpoint or model choice
+		llm.InvalidateModelCache("")
+	}
+	// New settings may have fixed an endpoint, give every provider a fresh chance
+	llm.ResetBreakers()
 	a.settings = &settings
+	a.cache.SetLimits(settings.cacheLimits())
 
 	settingsPath := a.getSettingsPath()
 	data, err := json.MarshalIndent(settings, "", "  ")
@@ -117,10 +172,16 @@ func (a *App) GetSettings() AppSettings {
 		return AppSettings{
 			APIProvider:    "ollama",
 			APIKey:         "dummy-key",
-			APIEndpoint:    "http://210.61.209.139:45014/v1/",
+			APIEndpoint:    llm.DefaultGPTOSSEndpoint,
 			FloatingIcon:   "🌸",
 			Language:       "zh-TW",
-			OllamaEndpoint: "http://127.0.0.1:11434",
+			OllamaEndpoint: ocr.DefaultEndpoint,
+			GeminiEndpoint: llm.DefaultGeminiEndpoint,
+
+			AnthropicEndpoint: llm.DefaultAnthropicEndpoint,
+			MaxTokens:         2048,
+
+			OpenAIEndpoint: llm.DefaultOpenAIEndpoint,
 		}
 	}
 	return *a.settings
@@ -136,7 +197,7 @@ func (a *App) startup(ctx context.Context) {
 		time.Sleep(2 * time.Second) // Wait for app to fully initialize
 		endpoint := a.settings.OllamaEndpoint
 		if endpoint == "" {
-			endpoint = "http://127.0.0.1:11434"
+			endpoint = ocr.DefaultEndpoint
 		}
 		
 		testURL := strings.TrimSuffix(endpoint, "/") + "/api/tags"
@@ -262,82 +323,150 @@ func (a *App) ReadScreenshotAsBase64(path string) (string, error) {
 	return base64Str, nil
 }
 
-// ExtractTextFromScreenshot uses Ollama to extract text from screenshot
+// ExtractTextFromScreenshot reads the text in the screenshot with the OCR
+// engine chosen in settings
 func (a *App) ExtractTextFromScreenshot(screenshotBase64 string) (string, error) {
-	ctx := a.ctx
-	if ctx == nil {
-		ctx = context.Background()
-	}
-	
-	endpoint := a.settings.OllamaEndpoint
-	if endpoint == "" {
-		endpoint = "http://127.0.0.1:11434"
-	}
-	
-	log.Printf("[OCR] Extracting text from screenshot using Ollama")
-	log.Printf("[OCR] Endpoint: %s", endpoint)
-	log.Printf("[OCR] Screenshot base64 length: %d", len(screenshotBase64))
-	
-	extractedText, err := ocr.ExtractTextFromImage(ctx, screenshotBase64, endpoint)
+	return a.extractText(a.baseContext(), screenshotBase64)
+}
+
+// extractText runs OCR on the screenshot, stopping when ctx is cancelled
+func (a *App) extractText(ctx context.Context, screenshotBase64 string) (string, error) {
+	result, err := a.recognize(ctx, screenshotBase64)
 	if err != nil {
-		log.Printf("[OCR] Failed to extract text: %v", err)
 		return "", err
 	}
-	
-	log.Printf("[OCR] Successfully extracted text (length: %d)", len(extractedText))
-	return extractedText, nil
+	return result.Text, nil
 }
 
-// QueryLLM sends a query with screenshot to the configured LLM provider
-func (a *App) QueryLLM(query string, screenshotBase64 string, language string) (string, error) {
+// providerConfig builds the connection settings for the named provider
+func (a *App) providerConfig(name string) llm.Config {
+	return a.settings.providerConfig(name)
+}
+
+// providerConfig builds the connection settings for the named provider
+func (s *AppSettings) providerConfig(name string) llm.Config {
+	cfg := llm.Config{
+		Endpoint:  s.APIEndpoint,
+		APIKey:    s.APIKey,
+		Model:     s.Models[name],
+		MaxTokens: s.MaxTokens,
+	}
+	switch name {
+	case "ollama":
+		cfg.Endpoint = s.OllamaEndpoint
+	case "gemini":
+		cfg.Endpoint = s.GeminiEndpoint
+	case "anthropic":
+		cfg.Endpoint = s.AnthropicEndpoint
+	case "openai":
+		cfg.Endpoint = s.OpenAIEndpoint
+		cfg.Vision = s.OpenAIVision
+	}
+	return cfg
+}
+
+// ollamaModel returns the model chosen for Ollama, or the default vision model
+func (s *AppSettings) ollamaModel() string {
+	if model := s.Models["ollama"]; model != "" {
+		return model
+	}
+	return ocr.DefaultModel
+}
+
+// ListModels asks the provider which models it can serve. The settings
+// argument holds the values currently shown in the settings form, so models
+// can be listed before they are saved.
+func (a *App) ListModels(provider string, settings AppSettings) ([]string, error) {
 	ctx := a.ctx
 	if ctx == nil {
 		ctx = context.Background()
 	}
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
 
-	log.Printf("[QueryLLM] Starting query with provider: %s", a.settings.APIProvider)
-	log.Printf("[QueryLLM] Query length: %d, Screenshot base64 length: %d", len(query), len(screenshotBase64))
-	
-	// If screenshot is provided and provider is NOT Ollama, extract text using Ollama first
-	var extractedText string
-	var shouldSendImage bool = false
-	
-	if screenshotBase64 != "" && a.settings.APIProvider != "ollama" {
-		log.Printf("[QueryLLM] Screenshot provided (length: %d), extracting text with Ollama...", len(screenshotBase64))
-		text, err := a.ExtractTextFromScreenshot(screenshotBase64)
-		if err != nil {
-			log.Printf("[QueryLLM] Warning: OCR failed, continuing without extracted text: %v", err)
-		} else if text != "" {
-			extractedText = text
-			log.Printf("[QueryLLM] OCR extracted text: %s", extractedText[:min(100, len(extractedText))])
-			
-			// Append extracted text to query
-			if query != "" {
-				query = query + "\n\n[圖片中的文字內容]\n" + extractedText
-			} else {
-				query = "[圖片中的文字內容]\n" + extractedText
-			}
+	p, err := llm.NewProvider(provider, settings.providerConfig(provider))
+	if err != nil {
+		return nil, err
+	}
+
+	lister, ok := p.(llm.ModelLister)
+	if !ok {
+		// Hosted APIs without a models endpoint offer their known defaults
+		return p.Models(), nil
+	}
+
+	models, err := lister.ListModels(ctx)
+	if err != nil {
+		log.Printf("[Models] Failed to list %s models: %v", provider, err)
+		return nil, err
+	}
+	log.Printf("[Models] %s offers %d models", provider, len(models))
+	return models, nil
+}
+
+// providerChain builds the primary provider followed by the configured
+// fallbacks. Unknown names are skipped, and Ollama is used when nothing valid
+// is configured.
+func (a *App) providerChain() []llm.Provider {
+	names := append([]string{a.settings.APIProvider}, a.settings.FallbackProviders...)
+
+	seen := make(map[string]bool)
+	chain := make([]llm.Provider, 0, len(names))
+	for _, name := range names {
+		if name == "" || seen[name] {
+			continue
 		}
-		
-		// Check if provider supports multimodal
-		// GPT-OSS doesn't support images, only Gemini does
-		if a.settings.APIProvider == "gemini" {
-			shouldSendImage = true
+		seen[name] = true
+
+		provider, err := llm.NewProvider(name, a.providerConfig(name))
+		if err != nil {
+			log.Printf("[QueryLLM] %v, skipping", err)
+			continue
 		}
+		chain = append(chain, provider)
 	}
 
-	if a.settings == nil {
-		return "", fmt.Errorf("Settings not initialized. Please configure your API settings.")
+	if len(chain) == 0 {
+		log.Printf("[QueryLLM] No valid provider configured, falling back to ollama")
+		provider, _ := llm.NewProvider("ollama", a.providerConfig("ollama"))
+		chain = append(chain, provider)
+	}
+	return chain
+}
+
+// wrapProvider adds the tool loop, the response cache and usage metrics
+// around a provider built by llm.NewProvider
+func (a *App) wrapProvider(provider llm.Provider, language string) llm.Provider {
+	if a.settings.EnableTools {
+		provider = llm.WithTools(provider, a.assistantTools(language), llm.DefaultMaxToolIterations)
 	}
+	provider = llm.WithCache(provider, a.responseCache(), a.cacheParams(provider.Name()))
+	return llm.WithMetrics(provider, a.metrics)
+}
 
-	// Only check API key for providers that require it (not gptoss)
-	if a.settings.APIProvider != "gptoss" && a.settings.APIKey == "" {
-		return "", fmt.Errorf("API key not configured. Please set your API key in Settings.")
+// QueryLLM sends a query with screenshot to the configured LLM provider
+func (a *App) QueryLLM(query string, screenshotBase64 string, language string) (string, error) {
+	resp, err := a.queryLLM(a.baseContext(), "", query, screenshotBase64, language, nil, nil)
+	if err != nil {
+		return "", err
 	}
+	return resp.Text, nil
+}
 
-	var result string
-	var err error
-	var model string
+// queryLLM runs a query against the configured provider, moving on to the
+// fallback providers when it is down. A non-empty threadID replays the
+// earlier turns of that thread, and a non-nil onChunk streams the answer
+// when the provider supports it. Cancelling ctx stops both the OCR step and
+// the provider call. A ctx marked with cache.WithBypass skips cached OCR
+// text and answers.
+func (a *App) queryLLM(ctx context.Context, threadID string, query string, screenshotBase64 string, language string, onChunk llm.StreamFunc, onCondensed func(CondenseNotice)) (*llm.Response, error) {
+	if a.settings == nil {
+		return nil, fmt.Errorf("Settings not initialized. Please configure your API settings.")
+	}
+
+	chain := a.providerChain()
+	log.Printf("[QueryLLM] Starting query with provider: %s (%d in chain)", chain[0].Name(), len(chain))
+	log.Printf("[QueryLLM] Query length: %d, Screenshot base64 length: %d", len(query), len(screenshotBase64))
 
 	// Use provided language or fall back to settings
 	if language == "" {
@@ -347,130 +476,126 @@ func (a *App) QueryLLM(query string, screenshotBase64 string, language string) (
 		language = "zh-TW" // Default to Chinese
 	}
 
-	switch a.settings.APIProvider {
-	case "ollama":
-		// Ollama 本地模型
-		endpoint := a.settings.OllamaEndpoint
-		if endpoint == "" {
-			endpoint = "http://127.0.0.1:11434"
-		}
-		model = "qwen3-vl:4b"
-		// Ollama 支持圖片，如果有截圖就發送
-		imageToSend := ""
-		if screenshotBase64 != "" {
-			imageToSend = screenshotBase64
-		}
-		result, err = ocr.QueryOllama(ctx, query, imageToSend, endpoint, language)
-	case "gptoss":
-		// GPT-OSS-120B (不支持圖片，只發送文字)
-		endpoint := a.settings.APIEndpoint
-		if endpoint == "" {
-			endpoint = "http://210.61.209.139:45014/v1/"
-		}
-		model = "gpt-oss-120b"
-		// Don't send image to GPT-OSS, only send extracted text
-		result, err = llm.QueryGPTOSS(ctx, query, "", a.settings.APIKey, endpoint, language)
-	case "gemini":
-		model = "gemini-2.0-flash-lite"
-		// Gemini supports multimodal, send image if available
-		imageToSend := ""
-		if shouldSendImage {
-			imageToSend = screenshotBase64
-		}
-		result, err = llm.QueryGemini(ctx, query, imageToSend, a.settings.APIKey, language)
-	default:
-		// Default to Ollama
-		endpoint := a.settings.OllamaEndpoint
-		if endpoint == "" {
-			endpoint = "http://127.0.0.1:11434"
-		}
-		model = "qwen3-vl:4b"
-		imageToSend := ""
-		if screenshotBase64 != "" {
-			imageToSend = screenshotBase64
-		}
-		result, err = ocr.QueryOllama(ctx, query, imageToSend, endpoint, language)
+	// Look up the screenshot before the query, the capture_screen tool saves
+	// newer ones. Only link it when one was sent so threads replay the right image.
+	screenshotPath := ""
+	if screenshotBase64 != "" {
+		screenshotPath, _ = getLastScreenshotPath()
 	}
 
-	if err != nil {
-		log.Printf("[QueryLLM] ERROR: %v", err)
-		return "", err
+	// Condensing long inputs goes to the plain providers, not the tool loop
+	bases := make(map[string]llm.Provider, len(chain))
+	for _, provider := range chain {
+		bases[provider.Name()] = provider
 	}
 
-	log.Printf("[QueryLLM] Success! Response length: %d", len(result))
-
-	// Save to history
-	if a.history != nil {
-		screenshotPath, _ := getLastScreenshotPath()
-		conv := history.Conversation{
-			Timestamp:      time.Now(),
-			Question:       query,
-			Answer:         result,
-			ScreenshotPath: screenshotPath,
-			Provider:       a.settings.APIProvider,
-			Model:          model,
-		}
-		if err := a.history.Save(conv); err != nil {
-			log.Printf("Warning: failed to save conversation to history: %v", err)
-		}
+	for i, provider := range chain {
+		chain[i] = a.wrapProvider(provider, language)
 	}
 
-	return result, nil
-}
+	// OCR runs at most once, the first time a provider without vision is tried
+	ocrDone := false
+	ocrQuery := query
 
-// QueryLLMWithWebSearch sends a query with web search enabled using Ollama
-func (a *App) QueryLLMWithWebSearch(query string, screenshotBase64 string, language string) (string, error) {
-	ctx := a.ctx
-	if ctx == nil {
-		ctx = context.Background()
-	}
-
-	log.Printf("[QueryLLMWithWebSearch] Starting query with web search enabled")
-	log.Printf("[QueryLLMWithWebSearch] Query: %s", query)
+	prepare := func(provider llm.Provider) (llm.Request, error) {
+		vision := provider.Capabilities().Vision
+		req := llm.Request{
+			Query:    query,
+			Language: language,
+			History:  a.threadHistory(threadID, vision, historyBudget(provider, a.settings.MaxTokens)),
+		}
+		if provider.Capabilities().Streaming {
+			req.OnChunk = onChunk
+		}
+		if screenshotBase64 == "" {
+			return req, nil
+		}
+		if vision {
+			req.ImageBase64 = screenshotBase64
+			return req, nil
+		}
 
-	// Get Ollama endpoint
-	endpoint := a.settings.OllamaEndpoint
-	if endpoint == "" {
-		endpoint = "http://127.0.0.1:11434"
+		// Providers without vision get the screenshot as OCR text instead
+		if !ocrDone {
+			ocrDone = true
+			log.Printf("[QueryLLM] Screenshot provided (length: %d), extracting text...", len(screenshotBase64))
+			text, err := a.extractText(ctx, screenshotBase64)
+			if ctx.Err() != nil {
+				return req, requestError(ctx, err)
+			}
+			if err != nil {
+				log.Printf("[QueryLLM] Warning: OCR failed, continuing without extracted text: %v", err)
+			} else if text != "" {
+				log.Printf("[QueryLLM] OCR extracted text: %s", text[:min(100, len(text))])
+
+				// Append extracted text to query
+				if query != "" {
+					ocrQuery = query + "\n\n[圖片中的文字內容]\n" + text
+				} else {
+					ocrQuery = "[圖片中的文字內容]\n" + text
+				}
+			}
+		}
+		req.Query = ocrQuery
+		return req, nil
 	}
 
-	// If screenshot is provided, extract text first
-	if screenshotBase64 != "" {
-		log.Printf("[QueryLLMWithWebSearch] Screenshot provided, extracting text...")
-		text, err := a.ExtractTextFromScreenshot(screenshotBase64)
+	// Inputs too long for a provider are condensed once per provider
+	fitted := make(map[string]string)
+	build := func(provider llm.Provider) (llm.Request, error) {
+		req, err := prepare(provider)
 		if err != nil {
-			log.Printf("[QueryLLMWithWebSearch] Warning: OCR failed: %v", err)
-		} else if text != "" {
-			query = query + "\n\n[圖片中的文字內容]\n" + text
+			return req, err
 		}
+		name := provider.Name()
+		if fittedQuery, ok := fitted[name]; ok {
+			req.Query = fittedQuery
+			return req, nil
+		}
+		fittedQuery, notice, err := a.fitQuery(ctx, bases[name], req)
+		if err != nil {
+			return req, requestError(ctx, err)
+		}
+		if notice != nil && onCondensed != nil {
+			onCondensed(*notice)
+		}
+		fitted[name] = fittedQuery
+		req.Query = fittedQuery
+		return req, nil
 	}
 
-	// Use Ollama with web search
-	result, err := ocr.QueryOllamaWithWebSearch(ctx, query, endpoint, language)
+	resp, provider, err := llm.QueryWithFallback(ctx, chain, llm.DefaultRetryPolicy, build)
 	if err != nil {
-		log.Printf("[QueryLLMWithWebSearch] ERROR: %v", err)
-		return "", err
+		log.Printf("[QueryLLM] ERROR: %v", err)
+		return nil, requestError(ctx, err)
 	}
+	result := resp.Text
 
-	log.Printf("[QueryLLMWithWebSearch] Success! Response length: %d", len(result))
+	log.Printf("[QueryLLM] Success from %s! Response length: %d", provider.Name(), len(result))
 
 	// Save to history
 	if a.history != nil {
-		screenshotPath, _ := getLastScreenshotPath()
+		question := query
+		if !provider.Capabilities().Vision {
+			question = ocrQuery
+		}
 		conv := history.Conversation{
+			ThreadID:       threadID,
 			Timestamp:      time.Now(),
-			Question:       query + " [聯網搜尋]",
+			Question:       question,
 			Answer:         result,
 			ScreenshotPath: screenshotPath,
-			Provider:       "ollama",
-			Model:          "qwen3-vl:4b",
+			Provider:       provider.Name(),
+			Model:          resp.Model,
+			Reasoning:      resp.Reasoning,
+			ToolCalls:      historyToolCalls(resp.ToolCalls),
 		}
 		if err := a.history.Save(conv); err != nil {
 			log.Printf("Warning: failed to save conversation to history: %v", err)
 		}
 	}
 
-	return result, nil
+	return resp, nil
 }
 
 // OpenDevTools opens the developer tools window
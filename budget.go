@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"github.com/Kelen/Korner/internal/llm"
+	"github.com/Kelen/Korner/internal/tokens"
+)
+
+// attachmentMarkers start the parts of a query that hold file contents or
+// OCR text rather than the user's own words
+var attachmentMarkers = []string{
+	"\n\n--- 檔案內容 ---\n",
+	"\n\n[圖片中的文字內容]\n",
+	"[圖片中的文字內容]\n",
+}
+
+// CondenseNotice tells the frontend that a long input was summarized to fit
+// the model's context window
+type CondenseNotice struct {
+	OriginalTokens int  `json:"originalTokens"`
+	Tokens         int  `json:"tokens"`
+	Chunks         int  `json:"chunks"`
+	Truncated      bool `json:"truncated"` // Condensing failed or was not enough and the text was cut
+}
+
+// splitAttachment separates the user's question from attached file
+// contents or OCR text. A query without attachments is all question.
+func splitAttachment(query string) (string, string) {
+	cut := -1
+	for _, marker := range attachmentMarkers {
+		if i := strings.Index(query, marker); i != -1 && (cut == -1 || i < cut) {
+			cut = i
+		}
+	}
+	if cut == -1 {
+		return query, ""
+	}
+	return query[:cut], query[cut:]
+}
+
+// fitQuery returns req.Query condensed to what fits p's context window next
+// to the thread history and screenshot. The user's own question is kept
+// word for word and only the attached text is summarized, unless the
+// question alone is too long.
+func (a *App) fitQuery(ctx context.Context, p llm.Provider, req llm.Request) (string, *CondenseNotice, error) {
+	budget := llm.QueryBudget(p, req, a.settings.MaxTokens)
+	if tokens.Estimate(req.Query) <= budget {
+		return req.Query, nil, nil
+	}
+
+	question, attachment := splitAttachment(req.Query)
+	attachmentBudget := budget - tokens.Estimate(question)
+	if attachment == "" || attachmentBudget < budget/4 {
+		question, attachment = "", req.Query
+		attachmentBudget = budget
+	}
+
+	log.Printf("[Budget] Query needs %d tokens, %s allows %d", tokens.Estimate(req.Query), p.Name(), budget)
+	condensed, err := llm.Condense(ctx, p, attachment, question, attachmentBudget, req.Language)
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", nil, ctx.Err()
+		}
+		// Better a cut answer than none when the model fails to summarize
+		log.Printf("[Budget] Warning: condensing failed, truncating instead: %v", err)
+		text := tokens.Truncate(attachment, attachmentBudget)
+		condensed = &llm.Condensed{
+			Text:           text,
+			OriginalTokens: tokens.Estimate(attachment),
+			Tokens:         tokens.Estimate(text),
+			Truncated:      true,
+		}
+	}
+
+	notice := &CondenseNotice{
+		OriginalTokens: condensed.OriginalTokens,
+		Tokens:         condensed.Tokens,
+		Chunks:         condensed.Chunks,
+		Truncated:      condensed.Truncated,
+	}
+	if question == "" {
+		return condensed.Text, notice, nil
+	}
+	return strings.TrimRight(question, "\n") + "\n\n[附加內容過長，以下為摘要]\n" + condensed.Text, notice, nil
+}
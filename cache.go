@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Kelen/Korner/internal/cache"
+)
+
+// openCache opens the response cache with the limits from the settings
+func (a *App) openCache() {
+	ttl, maxBytes := a.settings.cacheLimits()
+	c, err := cache.New(cache.DefaultDir(), ttl, maxBytes)
+	if err != nil {
+		log.Printf("Warning: failed to open response cache: %v", err)
+		return
+	}
+	a.cache = c
+}
+
+// cacheLimits converts the cache settings, zero values use the defaults
+func (s *AppSettings) cacheLimits() (time.Duration, int64) {
+	return time.Duration(s.CacheTTLHours) * time.Hour, int64(s.CacheMaxMB) << 20
+}
+
+// responseCache returns the cache, or nil when the user turned it off
+func (a *App) responseCache() *cache.Cache {
+	if a.settings == nil || a.settings.DisableCache {
+		return nil
+	}
+	return a.cache
+}
+
+// cacheParams lists the settings besides prompt and model that change an answer
+func (a *App) cacheParams(provider string) string {
+	cfg := a.providerConfig(provider)
+	return fmt.Sprintf("endpoint=%s max=%d tools=%t", cfg.Endpoint, cfg.MaxTokens, a.settings.EnableTools)
+}
+
+// withCacheBypass marks ctx so the request skips cached OCR and answers
+func withCacheBypass(ctx context.Context, noCache bool) context.Context {
+	if noCache {
+		log.Printf("[Cache] Bypassed for this request")
+		return cache.WithBypass(ctx)
+	}
+	return ctx
+}
+
+// GetCacheStats returns how many answers and OCR results are cached
+func (a *App) GetCacheStats() cache.Stats {
+	return a.cache.Stats()
+}
+
+// ClearCache removes every cached answer and OCR result
+func (a *App) ClearCache() error {
+	return a.cache.Clear()
+}
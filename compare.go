@@ -0,0 +1,200 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Kelen/Korner/internal/history"
+	"github.com/Kelen/Korner/internal/llm"
+)
+
+// CompareAnswer is the answer of one provider in a comparison
+type CompareAnswer struct {
+	RequestID      string `json:"requestId"` // Its "llm-stream" events carry this ID
+	Provider       string `json:"provider"`
+	Model          string `json:"model,omitempty"`
+	Text           string `json:"text,omitempty"`
+	Reasoning      string `json:"reasoning,omitempty"`
+	LatencyMs      int64  `json:"latencyMs"`
+	Error          string `json:"error,omitempty"`
+	ConversationID string `json:"conversationId,omitempty"` // History entry, see PickCompareAnswer
+}
+
+// CompareResult holds the answers of a CompareQuery in the requested order
+type CompareResult struct {
+	GroupID string          `json:"groupId"`
+	Answers []CompareAnswer `json:"answers"`
+}
+
+// compareRequestID returns the stream ID of one provider's answer
+func compareRequestID(requestID string, provider string) string {
+	return requestID + "/" + provider
+}
+
+// CompareQuery asks several providers the same question side by side, at
+// most llm.DefaultCompareConcurrency at a time. Each answer streams as
+// "llm-stream" events tagged with requestID + "/" + provider, and the
+// answers are saved to history as one group. CancelRequest(requestID)
+// stops all of them.
+func (a *App) CompareQuery(requestID string, query string, screenshotBase64 string, providers []string) (*CompareResult, error) {
+	if a.settings == nil {
+		return nil, fmt.Errorf("Settings not initialized. Please configure your API settings.")
+	}
+	ctx, done := a.beginRequest(requestID)
+	defer done()
+
+	language := a.settings.Language
+	if language == "" {
+		language = "zh-TW"
+	}
+
+	seen := make(map[string]bool)
+	var chain []llm.Provider
+	for _, name := range providers {
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		provider, err := llm.NewProvider(name, a.providerConfig(name))
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, provider)
+	}
+	if len(chain) < 2 {
+		return nil, fmt.Errorf("choose at least two providers to compare")
+	}
+	log.Printf("[Compare] Request %s asking %d providers", requestID, len(chain))
+
+	// Look up the screenshot before the fan-out, capture_screen saves newer ones
+	screenshotPath := ""
+	if screenshotBase64 != "" {
+		screenshotPath, _ = getLastScreenshotPath()
+	}
+
+	// Providers without vision share one OCR pass, done before the fan-out
+	ocrQuery := query
+	if screenshotBase64 != "" {
+		for _, provider := range chain {
+			if provider.Capabilities().Vision {
+				continue
+			}
+			text, err := a.extractText(ctx, screenshotBase64)
+			if ctx.Err() != nil {
+				return nil, requestError(ctx, err)
+			}
+			if err != nil {
+				log.Printf("[Compare] Warning: OCR failed, continuing without extracted text: %v", err)
+			} else if text != "" {
+				ocrQuery = query + "\n\n[圖片中的文字內容]\n" + text
+			}
+			break
+		}
+	}
+
+	bases := make(map[string]llm.Provider, len(chain))
+	for i, provider := range chain {
+		bases[provider.Name()] = provider
+		chain[i] = a.wrapProvider(provider, language)
+	}
+
+	build := func(provider llm.Provider) (llm.Request, error) {
+		streamID := compareRequestID(requestID, provider.Name())
+		req := llm.Request{Query: query, Language: language}
+		if provider.Capabilities().Streaming {
+			req.OnChunk = func(chunk string) {
+				a.emitStream(StreamEvent{RequestID: streamID, Chunk: chunk})
+			}
+		}
+		if screenshotBase64 != "" {
+			if provider.Capabilities().Vision {
+				req.ImageBase64 = screenshotBase64
+			} else {
+				req.Query = ocrQuery
+			}
+		}
+
+		fitted, notice, err := a.fitQuery(ctx, bases[provider.Name()], req)
+		if err != nil {
+			return req, err
+		}
+		if notice != nil {
+			a.emitStream(StreamEvent{RequestID: streamID, Condensed: notice})
+		}
+		req.Query = fitted
+		return req, nil
+	}
+
+	answers := llm.Compare(ctx, chain, llm.DefaultCompareConcurrency, build, func(i int, answer llm.Answer) {
+		event := StreamEvent{RequestID: compareRequestID(requestID, answer.Provider.Name()), Done: true}
+		if answer.Err != nil {
+			event.Error = requestError(ctx, answer.Err).Error()
+		} else {
+			event.Text, event.Reasoning = answer.Response.Text, answer.Response.Reasoning
+		}
+		a.emitStream(event)
+	})
+	if ctx.Err() != nil {
+		return nil, requestError(ctx, ctx.Err())
+	}
+
+	result := &CompareResult{GroupID: "compare-" + history.NewID()}
+	for _, answer := range answers {
+		name := answer.Provider.Name()
+		entry := CompareAnswer{
+			RequestID: compareRequestID(requestID, name),
+			Provider:  name,
+			LatencyMs: answer.Latency.Milliseconds(),
+		}
+		if answer.Err != nil {
+			log.Printf("[Compare] %s failed: %v", name, answer.Err)
+			entry.Error = answer.Err.Error()
+			result.Answers = append(result.Answers, entry)
+			continue
+		}
+		entry.Model, entry.Text, entry.Reasoning = answer.Response.Model, answer.Response.Text, answer.Response.Reasoning
+
+		if a.history != nil {
+			conv := history.Conversation{
+				ID:             history.NewID(),
+				Timestamp:      time.Now(),
+				Question:       query,
+				Answer:         entry.Text,
+				ScreenshotPath: screenshotPath,
+				Provider:       name,
+				Model:          entry.Model,
+				Reasoning:      entry.Reasoning,
+				ToolCalls:      historyToolCalls(answer.Response.ToolCalls),
+				GroupID:        result.GroupID,
+				LatencyMs:      entry.LatencyMs,
+			}
+			if err := a.history.Save(conv); err != nil {
+				log.Printf("Warning: failed to save comparison to history: %v", err)
+			} else {
+				entry.ConversationID = conv.ID
+			}
+		}
+		result.Answers = append(result.Answers, entry)
+	}
+
+	log.Printf("[Compare] Request %s finished, group %s", requestID, result.GroupID)
+	return result, nil
+}
+
+// GetCompareGroup returns the saved answers of a comparison
+func (a *App) GetCompareGroup(groupID string) ([]history.Conversation, error) {
+	if a.history == nil {
+		return nil, fmt.Errorf("history manager not initialized")
+	}
+	return a.history.GetGroup(groupID)
+}
+
+// PickCompareAnswer marks conversationID as the better answer of its
+// comparison group
+func (a *App) PickCompareAnswer(groupID string, conversationID string) error {
+	if a.history == nil {
+		return fmt.Errorf("history manager not initialized")
+	}
+	return a.history.SetPreferred(groupID, conversationID)
+}
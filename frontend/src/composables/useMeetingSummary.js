@@ -7,8 +7,13 @@ export function useMeetingSummary() {
     const processingText = ref('');
     const showSummaryResult = ref(false);
     const summaryResult = ref('');
+    const summaryNotes = ref(null);
+    const cancelled = ref(false);
+    let requestId = null;
 
     const generateSummary = async (audioPath) => {
+        requestId = `summary-${Date.now()}`;
+        cancelled.value = false;
         isProcessing.value = true;
         processingProgress.value = 10;
         processingStatus.value = '正在轉錄音訊...';
@@ -20,29 +25,46 @@ export function useMeetingSummary() {
             }
         }, 500);
         
-        const summaryPromise = window.go.main.App.GenerateMeetingSummary(audioPath);
+        const summaryPromise = window.go.main.App.GenerateMeetingSummary(requestId, audioPath);
         
-        setTimeout(() => {
+        const statusTimer = setTimeout(() => {
             processingStatus.value = '正在生成會議摘要...';
             processingText.value = '使用 AI 分析會議內容';
             processingProgress.value = 70;
         }, 3000);
         
-        const summary = await summaryPromise;
-        clearInterval(progressInterval);
-        
-        processingProgress.value = 100;
-        processingStatus.value = '完成！';
-        summaryResult.value = summary;
-        showSummaryResult.value = true;
-        
-        isProcessing.value = false;
-        processingProgress.value = 0;
+        try {
+            const summary = await summaryPromise;
+            
+            processingProgress.value = 100;
+            processingStatus.value = '完成！';
+            summaryResult.value = summary.text;
+            summaryNotes.value = summary.notes || null;
+            showSummaryResult.value = true;
+        } finally {
+            clearInterval(progressInterval);
+            clearTimeout(statusTimer);
+            requestId = null;
+            isProcessing.value = false;
+            processingProgress.value = 0;
+        }
+    };
+
+    // 中止轉錄或摘要，後端會結束 Whisper 程序
+    const cancelSummary = async () => {
+        if (!requestId) return;
+        cancelled.value = true;
+        try {
+            await window.go.main.App.CancelRequest(requestId);
+        } catch (error) {
+            console.error('[MeetingSummary] Failed to cancel:', error);
+        }
     };
 
     const closeSummary = () => {
         showSummaryResult.value = false;
         summaryResult.value = '';
+        summaryNotes.value = null;
     };
 
     return {
@@ -52,7 +74,10 @@ export function useMeetingSummary() {
         processingText,
         showSummaryResult,
         summaryResult,
+        summaryNotes,
+        cancelled,
         generateSummary,
+        cancelSummary,
         closeSummary
     };
 }
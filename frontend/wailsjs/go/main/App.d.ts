@@ -1,58 +1,99 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
+import {cache} from '../models';
 import {history} from '../models';
 import {main} from '../models';
+import {metrics} from '../models';
+import {ocr} from '../models';
+import {prompts} from '../models';
+import {table} from '../models';
+
+export function CancelRequest(arg1:string):Promise<boolean>;
 
 export function CaptureScreenshot(arg1:number,arg2:number,arg3:number,arg4:number):Promise<string>;
 
+export function ClearCache():Promise<void>;
+
 export function ClearHistory():Promise<void>;
 
+export function CompareQuery(arg1:string,arg2:string,arg3:string,arg4:Array<string>):Promise<main.CompareResult>;
+
 export function DeleteHistoryItem(arg1:string):Promise<void>;
 
 export function ExportHistoryToText(arg1:string):Promise<void>;
 
+export function ExportTable(arg1:table.Table,arg2:string):Promise<string>;
+
+export function ExtractTable(arg1:string,arg2:string,arg3:string):Promise<main.TableResult>;
+
 export function ExtractTextFromScreenshot(arg1:string):Promise<string>;
 
-export function GenerateMeetingSummary(arg1:string):Promise<string>;
+export function GenerateMeetingSummary(arg1:string,arg2:string):Promise<main.MeetingSummaryResult>;
 
 export function GetAllHistory():Promise<Array<history.Conversation>>;
 
+export function GetCacheStats():Promise<cache.Stats>;
+
+export function GetCompareGroup(arg1:string):Promise<Array<history.Conversation>>;
+
 export function GetDPIScale():Promise<number>;
 
 export function GetLastScreenshotPath():Promise<string>;
 
 export function GetPlatform():Promise<string>;
 
+export function GetPromptsDir():Promise<string>;
+
 export function GetRecentHistory(arg1:number):Promise<Array<history.Conversation>>;
 
 export function GetRecordingDuration():Promise<number>;
 
 export function GetScreenSize():Promise<number|number>;
 
+export function GetScreenshotLayout(arg1:string):Promise<ocr.Result>;
+
 export function GetSettings():Promise<main.AppSettings>;
 
+export function GetThreadHistory(arg1:string):Promise<Array<history.Conversation>>;
+
 export function GetTodayHistory():Promise<Array<history.Conversation>>;
 
+export function GetUsageStats(arg1:string):Promise<metrics.Summary>;
+
 export function GetWindowPosition():Promise<number|number>;
 
 export function HideWindow():Promise<void>;
 
 export function IsRecording():Promise<boolean>;
 
+export function ListActions():Promise<Array<prompts.Action>>;
+
+export function ListModels(arg1:string,arg2:main.AppSettings):Promise<Array<string>>;
+
 export function OpenDevTools():Promise<void>;
 
 export function OpenRecordingFolder():Promise<void>;
 
+export function PickCompareAnswer(arg1:string,arg2:string):Promise<void>;
+
 export function PositionWindowAt(arg1:number,arg2:number):Promise<void>;
 
 export function QueryLLM(arg1:string,arg2:string,arg3:string):Promise<string>;
 
-export function QueryLLMWithWebSearch(arg1:string,arg2:string,arg3:string):Promise<string>;
+export function QueryLLMJSON(arg1:string,arg2:string,arg3:string,arg4:string,arg5:string,arg6:Record<string, any>):Promise<any>;
+
+export function QueryLLMStream(arg1:string,arg2:string,arg3:string,arg4:string,arg5:string,arg6:boolean):Promise<string>;
+
+export function QueryLLMWithWebSearch(arg1:string,arg2:string,arg3:string,arg4:string):Promise<main.WebSearchAnswer>;
 
 export function ReadDocumentFile(arg1:string):Promise<string>;
 
 export function ReadScreenshotAsBase64(arg1:string):Promise<string>;
 
+export function ReloadPrompts():Promise<void>;
+
+export function RunAction(arg1:string,arg2:string,arg3:string,arg4:string,arg5:string,arg6:boolean):Promise<string>;
+
 export function SaveSettings(arg1:main.AppSettings):Promise<void>;
 
 export function SelectAudioFile():Promise<string>;
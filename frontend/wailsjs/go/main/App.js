@@ -2,14 +2,26 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
+export function CancelRequest(arg1) {
+  return window['go']['main']['App']['CancelRequest'](arg1);
+}
+
 export function CaptureScreenshot(arg1, arg2, arg3, arg4) {
   return window['go']['main']['App']['CaptureScreenshot'](arg1, arg2, arg3, arg4);
 }
 
+export function ClearCache() {
+  return window['go']['main']['App']['ClearCache']();
+}
+
 export function ClearHistory() {
   return window['go']['main']['App']['ClearHistory']();
 }
 
+export function CompareQuery(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['CompareQuery'](arg1, arg2, arg3, arg4);
+}
+
 export function DeleteHistoryItem(arg1) {
   return window['go']['main']['App']['DeleteHistoryItem'](arg1);
 }
@@ -18,18 +30,34 @@ export function ExportHistoryToText(arg1) {
   return window['go']['main']['App']['ExportHistoryToText'](arg1);
 }
 
+export function ExportTable(arg1, arg2) {
+  return window['go']['main']['App']['ExportTable'](arg1, arg2);
+}
+
+export function ExtractTable(arg1, arg2, arg3) {
+  return window['go']['main']['App']['ExtractTable'](arg1, arg2, arg3);
+}
+
 export function ExtractTextFromScreenshot(arg1) {
   return window['go']['main']['App']['ExtractTextFromScreenshot'](arg1);
 }
 
-export function GenerateMeetingSummary(arg1) {
-  return window['go']['main']['App']['GenerateMeetingSummary'](arg1);
+export function GenerateMeetingSummary(arg1, arg2) {
+  return window['go']['main']['App']['GenerateMeetingSummary'](arg1, arg2);
 }
 
 export function GetAllHistory() {
   return window['go']['main']['App']['GetAllHistory']();
 }
 
+export function GetCacheStats() {
+  return window['go']['main']['App']['GetCacheStats']();
+}
+
+export function GetCompareGroup(arg1) {
+  return window['go']['main']['App']['GetCompareGroup'](arg1);
+}
+
 export function GetDPIScale() {
   return window['go']['main']['App']['GetDPIScale']();
 }
@@ -42,6 +70,10 @@ export function GetPlatform() {
   return window['go']['main']['App']['GetPlatform']();
 }
 
+export function GetPromptsDir() {
+  return window['go']['main']['App']['GetPromptsDir']();
+}
+
 export function GetRecentHistory(arg1) {
   return window['go']['main']['App']['GetRecentHistory'](arg1);
 }
@@ -54,14 +86,26 @@ export function GetScreenSize() {
   return window['go']['main']['App']['GetScreenSize']();
 }
 
+export function GetScreenshotLayout(arg1) {
+  return window['go']['main']['App']['GetScreenshotLayout'](arg1);
+}
+
 export function GetSettings() {
   return window['go']['main']['App']['GetSettings']();
 }
 
+export function GetThreadHistory(arg1) {
+  return window['go']['main']['App']['GetThreadHistory'](arg1);
+}
+
 export function GetTodayHistory() {
   return window['go']['main']['App']['GetTodayHistory']();
 }
 
+export function GetUsageStats(arg1) {
+  return window['go']['main']['App']['GetUsageStats'](arg1);
+}
+
 export function GetWindowPosition() {
   return window['go']['main']['App']['GetWindowPosition']();
 }
@@ -74,6 +118,14 @@ export function IsRecording() {
   return window['go']['main']['App']['IsRecording']();
 }
 
+export function ListActions() {
+  return window['go']['main']['App']['ListActions']();
+}
+
+export function ListModels(arg1, arg2) {
+  return window['go']['main']['App']['ListModels'](arg1, arg2);
+}
+
 export function OpenDevTools() {
   return window['go']['main']['App']['OpenDevTools']();
 }
@@ -82,6 +134,10 @@ export function OpenRecordingFolder() {
   return window['go']['main']['App']['OpenRecordingFolder']();
 }
 
+export function PickCompareAnswer(arg1, arg2) {
+  return window['go']['main']['App']['PickCompareAnswer'](arg1, arg2);
+}
+
 export function PositionWindowAt(arg1, arg2) {
   return window['go']['main']['App']['PositionWindowAt'](arg1, arg2);
 }
@@ -90,8 +146,16 @@ export function QueryLLM(arg1, arg2, arg3) {
   return window['go']['main']['App']['QueryLLM'](arg1, arg2, arg3);
 }
 
-export function QueryLLMWithWebSearch(arg1, arg2, arg3) {
-  return window['go']['main']['App']['QueryLLMWithWebSearch'](arg1, arg2, arg3);
+export function QueryLLMJSON(arg1, arg2, arg3, arg4, arg5, arg6) {
+  return window['go']['main']['App']['QueryLLMJSON'](arg1, arg2, arg3, arg4, arg5, arg6);
+}
+
+export function QueryLLMStream(arg1, arg2, arg3, arg4, arg5, arg6) {
+  return window['go']['main']['App']['QueryLLMStream'](arg1, arg2, arg3, arg4, arg5, arg6);
+}
+
+export function QueryLLMWithWebSearch(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['QueryLLMWithWebSearch'](arg1, arg2, arg3, arg4);
 }
 
 export function ReadDocumentFile(arg1) {
@@ -102,6 +166,14 @@ export function ReadScreenshotAsBase64(arg1) {
   return window['go']['main']['App']['ReadScreenshotAsBase64'](arg1);
 }
 
+export function ReloadPrompts() {
+  return window['go']['main']['App']['ReloadPrompts']();
+}
+
+export function RunAction(arg1, arg2, arg3, arg4, arg5, arg6) {
+  return window['go']['main']['App']['RunAction'](arg1, arg2, arg3, arg4, arg5, arg6);
+}
+
 export function SaveSettings(arg1) {
   return window['go']['main']['App']['SaveSettings'](arg1);
 }
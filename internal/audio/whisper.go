@@ -1,6 +1,7 @@
 package audio
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
@@ -26,7 +27,8 @@ func DefaultTranscribeOptions() TranscribeOptions {
 
 // Transcribe transcribes an audio file and returns the text
 // Supports: wav, mp3, m4a, flac, ogg, opus, and other formats supported by ffmpeg
-func (w *WhisperTranscriber) Transcribe(audioPath string, options TranscribeOptions) (string, error) {
+// Cancelling ctx kills the Python process.
+func (w *WhisperTranscriber) Transcribe(ctx context.Context, audioPath string, options TranscribeOptions) (string, error) {
 	log.Printf("[Whisper] Starting transcription for: %s", audioPath)
 	
 	// Verify audio file exists
@@ -77,7 +79,7 @@ func (w *WhisperTranscriber) Transcribe(audioPath string, options TranscribeOpti
 	
 	// 執行 python -m whisper 命令，輸出 txt 格式，指定輸出目錄
 	// 對於 mp3 等壓縮格式，Whisper 會自動使用 ffmpeg 解碼
-	cmd := exec.Command(pythonCmd, "-m", "whisper", audioPath, 
+	cmd := exec.CommandContext(ctx, pythonCmd, "-m", "whisper", audioPath, 
 		"--model", "tiny", 
 		"--output_format", "txt",
 		"--output_dir", outputDir)
@@ -91,6 +93,10 @@ func (w *WhisperTranscriber) Transcribe(audioPath string, options TranscribeOpti
 	log.Printf("[Whisper] Executing Whisper command...")
 	output, err := cmd.CombinedOutput()
 	
+	if ctx.Err() != nil {
+		log.Printf("[Whisper] Transcription cancelled: %v", ctx.Err())
+		return "", ctx.Err()
+	}
 	if err != nil {
 		errorMsg := string(output)
 		log.Printf("[Whisper] Error: Command failed: %v", err)
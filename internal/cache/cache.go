@@ -0,0 +1,319 @@
+// Package cache stores OCR results and LLM answers on disk, keyed by a hash
+// of everything that determines them, so repeated questions about the same
+// screenshot return instantly.
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultTTL      = 24 * time.Hour
+	DefaultMaxBytes = 200 << 20 // 200 MB
+)
+
+// now is replaced in tests
+var now = time.Now
+
+// Cache is a size-capped on-disk store. Entries expire after the TTL and the
+// least recently used ones are evicted when the size cap is reached. A nil
+// *Cache is valid and never hits.
+type Cache struct {
+	mu       sync.Mutex
+	dir      string
+	ttl      time.Duration
+	maxBytes int64
+	size     int64
+	entries  map[string]*entry
+}
+
+type entry struct {
+	size     int64
+	accessed time.Time
+}
+
+// record is the file format of one entry
+type record struct {
+	Created time.Time       `json:"created"`
+	Value   json.RawMessage `json:"value"`
+}
+
+// Stats describes the current cache contents
+type Stats struct {
+	Entries  int   `json:"entries"`
+	Bytes    int64 `json:"bytes"`
+	MaxBytes int64 `json:"maxBytes"`
+}
+
+// DefaultDir returns ~/.korner/cache
+func DefaultDir() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		log.Printf("[Cache] Failed to get home directory: %v", err)
+		return "cache"
+	}
+	return filepath.Join(homeDir, ".korner", "cache")
+}
+
+// New opens the cache in dir, dropping expired entries left from earlier
+// runs. Zero ttl or maxBytes use the defaults.
+func New(dir string, ttl time.Duration, maxBytes int64) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("create cache directory: %w", err)
+	}
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read cache directory: %w", err)
+	}
+
+	c := &Cache{dir: dir, entries: make(map[string]*entry)}
+	c.setLimits(ttl, maxBytes)
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != ".json" {
+			continue
+		}
+		info, err := file.Info()
+		if err != nil {
+			continue
+		}
+		key := strings.TrimSuffix(file.Name(), ".json")
+		// The modification time is the last access, which is never before creation
+		if now().Sub(info.ModTime()) > c.ttl {
+			os.Remove(filepath.Join(dir, file.Name()))
+			continue
+		}
+		c.entries[key] = &entry{size: info.Size(), accessed: info.ModTime()}
+		c.size += info.Size()
+	}
+
+	c.mu.Lock()
+	c.evict()
+	c.mu.Unlock()
+	log.Printf("[Cache] Opened %s with %d entries (%d bytes)", dir, len(c.entries), c.size)
+	return c, nil
+}
+
+// SetLimits changes the TTL and size cap, evicting entries over the new cap
+func (c *Cache) SetLimits(ttl time.Duration, maxBytes int64) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.setLimits(ttl, maxBytes)
+	c.evict()
+}
+
+func (c *Cache) setLimits(ttl time.Duration, maxBytes int64) {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxBytes
+	}
+	c.ttl = ttl
+	c.maxBytes = maxBytes
+}
+
+// Get decodes the value stored under key into v and reports whether there
+// was a fresh entry
+func (c *Cache) Get(key string, v interface{}) bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return false
+	}
+	path := c.path(key)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		c.remove(key)
+		return false
+	}
+	var rec record
+	if err := json.Unmarshal(data, &rec); err != nil {
+		log.Printf("[Cache] Dropping unreadable entry %s: %v", key, err)
+		c.remove(key)
+		return false
+	}
+	if now().Sub(rec.Created) > c.ttl {
+		c.remove(key)
+		return false
+	}
+	if err := json.Unmarshal(rec.Value, v); err != nil {
+		log.Printf("[Cache] Dropping entry %s of another type: %v", key, err)
+		c.remove(key)
+		return false
+	}
+
+	e.accessed = now()
+	// The file time keeps the LRU order across restarts
+	os.Chtimes(path, e.accessed, e.accessed)
+	return true
+}
+
+// Put stores v under key, evicting the least recently used entries when the
+// cache grows past its size cap
+func (c *Cache) Put(key string, v interface{}) error {
+	if c == nil {
+		return nil
+	}
+	value, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("encode cache entry: %w", err)
+	}
+	data, err := json.Marshal(record{Created: now(), Value: value})
+	if err != nil {
+		return fmt.Errorf("encode cache entry: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if int64(len(data)) > c.maxBytes {
+		return nil
+	}
+	// Write to a temporary file first so a crash never leaves half an entry
+	tmp := c.path(key) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("write cache entry: %w", err)
+	}
+	if err := os.Rename(tmp, c.path(key)); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("write cache entry: %w", err)
+	}
+
+	if old, ok := c.entries[key]; ok {
+		c.size -= old.size
+	}
+	c.entries[key] = &entry{size: int64(len(data)), accessed: now()}
+	c.size += int64(len(data))
+	c.evict()
+	return nil
+}
+
+// Clear removes every entry
+func (c *Cache) Clear() error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var firstErr error
+	for key := range c.entries {
+		if err := os.Remove(c.path(key)); err != nil && !os.IsNotExist(err) && firstErr == nil {
+			firstErr = fmt.Errorf("clear cache: %w", err)
+		}
+		delete(c.entries, key)
+	}
+	c.size = 0
+	log.Printf("[Cache] Cleared")
+	return firstErr
+}
+
+// Stats returns the number and total size of the entries
+func (c *Cache) Stats() Stats {
+	if c == nil {
+		return Stats{}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return Stats{Entries: len(c.entries), Bytes: c.size, MaxBytes: c.maxBytes}
+}
+
+// evict drops the least recently used entries until the cache fits its cap.
+// The caller holds c.mu.
+func (c *Cache) evict() {
+	if c.size <= c.maxBytes {
+		return
+	}
+	keys := make([]string, 0, len(c.entries))
+	for key := range c.entries {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return c.entries[keys[i]].accessed.Before(c.entries[keys[j]].accessed)
+	})
+	for _, key := range keys {
+		if c.size <= c.maxBytes {
+			break
+		}
+		c.remove(key)
+	}
+}
+
+// remove deletes one entry. The caller holds c.mu.
+func (c *Cache) remove(key string) {
+	if e, ok := c.entries[key]; ok {
+		c.size -= e.size
+		delete(c.entries, key)
+	}
+	os.Remove(c.path(key))
+}
+
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.dir, key+".json")
+}
+
+// Key hashes parts into a cache key. Each part is length-prefixed so
+// ("ab", "c") and ("a", "bc") give different keys.
+func Key(parts ...string) string {
+	h := sha256.New()
+	var length [8]byte
+	for _, part := range parts {
+		binary.BigEndian.PutUint64(length[:], uint64(len(part)))
+		h.Write(length[:])
+		h.Write([]byte(part))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// HashBytes returns the hex SHA-256 of data, e.g. the bytes of a screenshot
+func HashBytes(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// HashBase64 hashes the decoded bytes of a base64 image, so the same
+// screenshot gives the same key however it was encoded
+func HashBase64(data string) string {
+	if i := strings.Index(data, ","); i != -1 && strings.HasPrefix(data, "data:") {
+		data = data[i+1:]
+	}
+	decoded, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return HashBytes([]byte(data))
+	}
+	return HashBytes(decoded)
+}
+
+type bypassKey struct{}
+
+// WithBypass marks ctx so lookups miss and fresh results replace the cached ones
+func WithBypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, bypassKey{}, true)
+}
+
+// Bypassed reports whether the request behind ctx asked to skip the cache
+func Bypassed(ctx context.Context) bool {
+	bypass, _ := ctx.Value(bypassKey{}).(bool)
+	return bypass
+}
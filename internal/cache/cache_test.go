@@ -0,0 +1,119 @@
+package cache
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPutGet(t *testing.T) {
+	c, err := New(t.TempDir(), 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := Key("ocr", "model", HashBytes([]byte("image")))
+	if err := c.Put(key, "extracted text"); err != nil {
+		t.Fatal(err)
+	}
+	var got string
+	if !c.Get(key, &got) || got != "extracted text" {
+		t.Fatalf("Get = %q, want the stored text", got)
+	}
+	if c.Get(Key("ocr", "model", HashBytes([]byte("other"))), &got) {
+		t.Error("Get hit for a key that was never stored")
+	}
+}
+
+func TestKeySeparatesParts(t *testing.T) {
+	if Key("ab", "c") == Key("a", "bc") {
+		t.Error("Key does not separate its parts")
+	}
+}
+
+func TestTTL(t *testing.T) {
+	defer func() { now = time.Now }()
+	start := time.Now()
+	now = func() time.Time { return start }
+
+	c, err := New(t.TempDir(), time.Hour, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Put("k", 42)
+
+	now = func() time.Time { return start.Add(2 * time.Hour) }
+	var got int
+	if c.Get("k", &got) {
+		t.Error("Get returned an expired entry")
+	}
+	if c.Stats().Entries != 0 {
+		t.Error("expired entry was not removed")
+	}
+}
+
+func TestEvictsLeastRecentlyUsed(t *testing.T) {
+	defer func() { now = time.Now }()
+	clock := time.Now()
+	now = func() time.Time { clock = clock.Add(time.Second); return clock }
+
+	value := strings.Repeat("x", 100)
+	c, err := New(t.TempDir(), 0, 400)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Put("a", value)
+	c.Put("b", value)
+	var got string
+	c.Get("a", &got) // a is now more recent than b
+	c.Put("c", value)
+
+	if c.Get("b", &got) {
+		t.Error("least recently used entry was not evicted")
+	}
+	if !c.Get("a", &got) || !c.Get("c", &got) {
+		t.Error("recent entries were evicted")
+	}
+	if stats := c.Stats(); stats.Bytes > 400 {
+		t.Errorf("cache holds %d bytes, cap is 400", stats.Bytes)
+	}
+}
+
+func TestReopen(t *testing.T) {
+	dir := t.TempDir()
+	c, err := New(dir, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Put("k", "v")
+
+	c, err = New(dir, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got string
+	if !c.Get("k", &got) || got != "v" {
+		t.Errorf("entry lost after reopening, got %q", got)
+	}
+}
+
+func TestNilCache(t *testing.T) {
+	var c *Cache
+	var got string
+	if c.Get("k", &got) {
+		t.Error("nil cache hit")
+	}
+	if err := c.Put("k", "v"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestBypass(t *testing.T) {
+	if Bypassed(context.Background()) {
+		t.Error("plain context is bypassed")
+	}
+	if !Bypassed(WithBypass(context.Background())) {
+		t.Error("WithBypass not detected")
+	}
+}
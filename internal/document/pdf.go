@@ -9,6 +9,9 @@ import (
 	"github.com/ledongthuc/pdf"
 )
 
+// MaxTextLength is the most characters read from one document
+const MaxTextLength = 100000
+
 // ExtractPDFText extracts text content from a PDF file
 func ExtractPDFText(filePath string) (string, error) {
 	f, r, err := pdf.Open(filePath)
@@ -41,14 +44,7 @@ func ExtractPDFText(filePath string) (string, error) {
 		textBuilder.WriteString(text)
 	}
 
-	result := textBuilder.String()
-	
-	// Limit total text length
-	if len(result) > 10000 {
-		result = result[:10000] + "\n\n[內容過長，已截斷]"
-	}
-
-	return result, nil
+	return limitText(textBuilder.String()), nil
 }
 
 // ReadTextFile reads a text file
@@ -64,12 +60,15 @@ func ReadTextFile(filePath string) (string, error) {
 		return "", fmt.Errorf("failed to read file: %w", err)
 	}
 
-	text := string(data)
-	
-	// Limit text length
-	if len(text) > 10000 {
-		text = text[:10000] + "\n\n[內容過長，已截斷]"
-	}
+	return limitText(string(data)), nil
+}
 
-	return text, nil
+// limitText cuts text at MaxTextLength characters. Long documents are
+// condensed to fit the model later, this only guards against huge files.
+func limitText(text string) string {
+	runes := []rune(text)
+	if len(runes) <= MaxTextLength {
+		return text
+	}
+	return string(runes[:MaxTextLength]) + "\n\n[內容過長，已截斷]"
 }
@@ -8,18 +8,50 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
+	"sync/atomic"
 	"time"
 )
 
 // Conversation represents a single conversation entry
 type Conversation struct {
-	ID             string    `json:"id"`
-	Timestamp      time.Time `json:"timestamp"`
-	Question       string    `json:"question"`
-	Answer         string    `json:"answer"`
-	ScreenshotPath string    `json:"screenshot_path,omitempty"`
-	Provider       string    `json:"provider"`
-	Model          string    `json:"model,omitempty"`
+	ID             string     `json:"id"`
+	ThreadID       string     `json:"thread_id,omitempty"`
+	Timestamp      time.Time  `json:"timestamp"`
+	Question       string     `json:"question"`
+	Answer         string     `json:"answer"`
+	ScreenshotPath string     `json:"screenshot_path,omitempty"`
+	Provider       string     `json:"provider"`
+	Model          string     `json:"model,omitempty"`
+	Reasoning      string     `json:"reasoning,omitempty"`  // The model's thinking before the answer
+	ToolCalls      []ToolCall `json:"tool_calls,omitempty"` // Tools the model used while answering
+	GroupID        string     `json:"group_id,omitempty"`   // Answers of several providers to one question, see GetGroup
+	Preferred      bool       `json:"preferred,omitempty"`  // Picked as the best answer of its group
+	LatencyMs      int64      `json:"latency_ms,omitempty"`
+	Table          *Table     `json:"table,omitempty"`   // Table extracted from the screenshot, see ExtractTable
+	Sources        []Source   `json:"sources,omitempty"` // Web pages the answer cites as [n]
+}
+
+// ToolCall records one tool the model called while answering
+type ToolCall struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+	Result    string `json:"result,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Table records a table read from the screenshot
+type Table struct {
+	Title   string     `json:"title"`
+	Columns []string   `json:"columns"`
+	Rows    [][]string `json:"rows"`
+}
+
+// Source records a web page the answer cites as [ID]
+type Source struct {
+	ID    int    `json:"id"`
+	Title string `json:"title"`
+	URL   string `json:"url"`
 }
 
 // Manager handles conversation history
@@ -47,10 +79,19 @@ func NewManager() (*Manager, error) {
 	}, nil
 }
 
+// idSeq tells apart IDs made within one tick of the clock, which on
+// Windows can be as coarse as half a millisecond
+var idSeq atomic.Uint64
+
+// NewID returns a unique conversation ID that sorts by creation time
+func NewID() string {
+	return fmt.Sprintf("%d-%d", time.Now().UnixNano(), idSeq.Add(1))
+}
+
 // Save saves a conversation to history
 func (m *Manager) Save(conv Conversation) error {
 	if conv.ID == "" {
-		conv.ID = fmt.Sprintf("%d", time.Now().UnixNano())
+		conv.ID = NewID()
 	}
 	if conv.Timestamp.IsZero() {
 		conv.Timestamp = time.Now()
@@ -171,6 +212,130 @@ func (m *Manager) GetAll() ([]Conversation, error) {
 	return allConversations, nil
 }
 
+// Search returns up to limit conversations whose question or answer
+// contains query, ignoring case, newest first
+func (m *Manager) Search(query string, limit int) ([]Conversation, error) {
+	conversations, err := m.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	query = strings.ToLower(strings.TrimSpace(query))
+	matches := []Conversation{}
+	for _, conv := range conversations {
+		if limit > 0 && len(matches) >= limit {
+			break
+		}
+		if query == "" ||
+			strings.Contains(strings.ToLower(conv.Question), query) ||
+			strings.Contains(strings.ToLower(conv.Answer), query) {
+			matches = append(matches, conv)
+		}
+	}
+	return matches, nil
+}
+
+// GetThread returns all conversations of a thread, oldest first
+func (m *Manager) GetThread(threadID string) ([]Conversation, error) {
+	if threadID == "" {
+		return []Conversation{}, nil
+	}
+
+	conversations, err := m.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	thread := []Conversation{}
+	for _, conv := range conversations {
+		if conv.ThreadID == threadID {
+			thread = append(thread, conv)
+		}
+	}
+
+	// GetAll is newest first, threads replay oldest first
+	sort.Slice(thread, func(i, j int) bool {
+		return thread[i].Timestamp.Before(thread[j].Timestamp)
+	})
+
+	return thread, nil
+}
+
+// GetGroup returns the answers of a comparison group in the order they
+// were saved
+func (m *Manager) GetGroup(groupID string) ([]Conversation, error) {
+	if groupID == "" {
+		return []Conversation{}, nil
+	}
+
+	conversations, err := m.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	group := []Conversation{}
+	for _, conv := range conversations {
+		if conv.GroupID == groupID {
+			group = append(group, conv)
+		}
+	}
+	sort.SliceStable(group, func(i, j int) bool {
+		return group[i].Timestamp.Before(group[j].Timestamp)
+	})
+
+	return group, nil
+}
+
+// SetPreferred marks the conversation id as the best answer of its group
+// and clears the mark from the other answers
+func (m *Manager) SetPreferred(groupID string, id string) error {
+	files, err := ioutil.ReadDir(m.historyDir)
+	if err != nil {
+		return fmt.Errorf("read history directory: %w", err)
+	}
+
+	found := false
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != ".json" {
+			continue
+		}
+		conversations, err := m.loadFile(file.Name())
+		if err != nil {
+			continue
+		}
+
+		changed := false
+		for i, conv := range conversations {
+			if groupID == "" || conv.GroupID != groupID {
+				continue
+			}
+			if conv.ID == id {
+				found = true
+			}
+			if preferred := conv.ID == id; conv.Preferred != preferred {
+				conversations[i].Preferred = preferred
+				changed = true
+			}
+		}
+		if !changed {
+			continue
+		}
+
+		data, err := json.MarshalIndent(conversations, "", "  ")
+		if err != nil {
+			return fmt.Errorf("marshal conversations: %w", err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(m.historyDir, file.Name()), data, 0644); err != nil {
+			return fmt.Errorf("write history file: %w", err)
+		}
+	}
+
+	if !found {
+		return fmt.Errorf("conversation %s not found in group %s", id, groupID)
+	}
+	return nil
+}
+
 // Delete deletes a conversation by ID
 func (m *Manager) Delete(id string) error {
 	files, err := ioutil.ReadDir(m.historyDir)
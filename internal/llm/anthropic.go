@@ -0,0 +1,283 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultAnthropicEndpoint is the public Anthropic API base URL
+	DefaultAnthropicEndpoint = "https://api.anthropic.com/v1"
+	// DefaultAnthropicModel is the Claude model used when none is configured
+	DefaultAnthropicModel = "claude-sonnet-4-5"
+	// anthropicVersion is the Messages API version header value
+	anthropicVersion = "2023-06-01"
+	// defaultMaxTokens is used when settings do not specify max_tokens
+	defaultMaxTokens = 2048
+)
+
+func init() {
+	Register("anthropic", func(cfg Config) Provider {
+		return &anthropicProvider{cfg: cfg}
+	})
+}
+
+// anthropicProvider adapts the Anthropic Messages API to the Provider interface
+type anthropicProvider struct {
+	cfg Config
+}
+
+func (p *anthropicProvider) Name() string { return "anthropic" }
+
+func (p *anthropicProvider) Capabilities() Capabilities {
+	return Capabilities{Vision: true, Streaming: true}
+}
+
+// Models lists the configured model, which is what Query sends
+func (p *anthropicProvider) Models() []string {
+	if p.cfg.Model != "" {
+		return []string{p.cfg.Model}
+	}
+	return []string{DefaultAnthropicModel}
+}
+
+func (p *anthropicProvider) Query(ctx context.Context, req Request) (*Response, error) {
+	return queryAnthropic(ctx, p.cfg, req)
+}
+
+// queryAnthropic sends the request to {endpoint}/messages
+func queryAnthropic(ctx context.Context, cfg Config, r Request) (*Response, error) {
+	if cfg.APIKey == "" {
+		return nil, errors.New("API key not configured. Please set your API key in Settings.")
+	}
+
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = DefaultAnthropicEndpoint
+	}
+	apiURL := strings.TrimSuffix(endpoint, "/")
+	if !strings.HasSuffix(apiURL, "/messages") {
+		apiURL += "/messages"
+	}
+	model := cfg.Model
+	if model == "" {
+		model = DefaultAnthropicModel
+	}
+	maxTokens := cfg.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = defaultMaxTokens
+	}
+	log.Printf("[Anthropic] Using endpoint: %s", apiURL)
+
+	messages := make([]AnthropicMessage, 0, len(r.History)*2+1)
+	for _, turn := range r.History {
+		messages = append(messages,
+			anthropicUserMessage(turn.Question, turn.ImageBase64),
+			AnthropicMessage{
+				Role:    "assistant",
+				Content: []AnthropicContent{{Type: "text", Text: turn.Answer}},
+			},
+		)
+	}
+	messages = append(messages, anthropicUserMessage(r.Query, r.ImageBase64))
+	if r.ImageBase64 != "" {
+		log.Printf("[Anthropic] Added image to request (base64 length: %d)", len(r.ImageBase64))
+	}
+
+	reqPayload := AnthropicRequest{
+		Model:     model,
+		MaxTokens: maxTokens,
+		System:    defaultSystemPrompt(r.Language, r.Query),
+		Messages:  messages,
+		Stream:    r.OnChunk != nil,
+	}
+
+	body, err := json.Marshal(reqPayload)
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+
+	httpClient := &http.Client{Timeout: 120 * time.Second}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", cfg.APIKey)
+	req.Header.Set("anthropic-version", anthropicVersion)
+
+	log.Printf("[Anthropic] Sending request with model: %s, max_tokens: %d", model, maxTokens)
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("http request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		log.Printf("[Anthropic] ERROR response: %s", string(bodyBytes))
+		return nil, anthropicError(resp.StatusCode, bodyBytes)
+	}
+
+	var responseText string
+	var usage Usage
+	if r.OnChunk != nil {
+		streamed, streamedUsage, err := readAnthropicStream(resp.Body, r.OnChunk)
+		if err != nil {
+			return nil, err
+		}
+		responseText = strings.TrimSpace(streamed)
+		usage = streamedUsage
+	} else {
+		var result AnthropicResponse
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			return nil, fmt.Errorf("decode response: %w", err)
+		}
+
+		var text strings.Builder
+		for _, block := range result.Content {
+			if block.Type == "text" {
+				text.WriteString(block.Text)
+			}
+		}
+		responseText = strings.TrimSpace(text.String())
+		if result.Model != "" {
+			model = result.Model
+		}
+		usage = result.Usage.usage()
+	}
+
+	if responseText == "" {
+		return nil, errors.New("empty response from API")
+	}
+
+	log.Printf("[Anthropic] Success! Response length: %d", len(responseText))
+	return &Response{Text: responseText, Model: model, Usage: usage}, nil
+}
+
+// anthropicUserMessage builds a user message with an optional base64 image block
+func anthropicUserMessage(text string, imageBase64 string) AnthropicMessage {
+	msg := AnthropicMessage{Role: "user"}
+	if imageBase64 != "" {
+		// Images go first, Claude reads them as context for the question
+		mimeType, data := splitDataURL(imageBase64)
+		msg.Content = append(msg.Content, AnthropicContent{
+			Type: "image",
+			Source: &AnthropicImageSource{
+				Type:      "base64",
+				MediaType: mimeType,
+				Data:      data,
+			},
+		})
+	}
+	msg.Content = append(msg.Content, AnthropicContent{Type: "text", Text: text})
+	return msg
+}
+
+// readAnthropicStream reads the Messages API event stream, passes every
+// text delta to onChunk and returns the full text with the usage reported
+// by the message_start and message_delta events
+func readAnthropicStream(body io.Reader, onChunk StreamFunc) (string, Usage, error) {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var full strings.Builder
+	var usage Usage
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+
+		var event AnthropicStreamEvent
+		if err := json.Unmarshal([]byte(strings.TrimSpace(strings.TrimPrefix(line, "data:"))), &event); err != nil {
+			log.Printf("[Anthropic] Skipping malformed event: %v", err)
+			continue
+		}
+
+		switch event.Type {
+		case "message_start":
+			if event.Message != nil {
+				usage = event.Message.Usage.usage()
+			}
+		case "message_delta":
+			if event.Usage != nil {
+				usage.CompletionTokens = event.Usage.OutputTokens
+				if event.Usage.InputTokens > 0 {
+					usage.PromptTokens = event.Usage.InputTokens
+				}
+			}
+		case "content_block_delta":
+			if event.Delta.Type == "text_delta" && event.Delta.Text != "" {
+				full.WriteString(event.Delta.Text)
+				onChunk(event.Delta.Text)
+			}
+		case "error":
+			if event.Error != nil {
+				return full.String(), usage, &StatusError{
+					StatusCode: anthropicStreamStatus[event.Error.Type],
+					Err:        anthropicErrorMessage(0, *event.Error),
+				}
+			}
+		case "message_stop":
+			return full.String(), usage, nil
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return full.String(), usage, fmt.Errorf("read stream: %w", err)
+	}
+	return full.String(), usage, nil
+}
+
+// anthropicError decodes an API error response into a message the UI can show
+func anthropicError(statusCode int, body []byte) error {
+	var apiErr AnthropicErrorResponse
+	if err := json.Unmarshal(body, &apiErr); err != nil || apiErr.Error.Message == "" {
+		return apiError(statusCode, body)
+	}
+	return &StatusError{StatusCode: statusCode, Err: anthropicErrorMessage(statusCode, apiErr.Error)}
+}
+
+// anthropicStreamStatus gives error events sent mid-stream, which carry no
+// HTTP status, the status Anthropic uses for the same error type
+var anthropicStreamStatus = map[string]int{
+	"invalid_request_error": http.StatusBadRequest,
+	"authentication_error":  http.StatusUnauthorized,
+	"permission_error":      http.StatusForbidden,
+	"not_found_error":       http.StatusNotFound,
+	"request_too_large":     http.StatusRequestEntityTooLarge,
+	"rate_limit_error":      http.StatusTooManyRequests,
+	"api_error":             http.StatusInternalServerError,
+	"overloaded_error":      529,
+}
+
+// anthropicErrorMessage maps Anthropic error types to user-facing messages
+func anthropicErrorMessage(statusCode int, apiErr AnthropicError) error {
+	switch apiErr.Type {
+	case "authentication_error", "permission_error":
+		return fmt.Errorf("Anthropic API key is invalid or lacks permission. Please check your API key in Settings. (%s)", apiErr.Message)
+	case "not_found_error":
+		return fmt.Errorf("Anthropic model or endpoint not found. Please check Settings. (%s)", apiErr.Message)
+	case "rate_limit_error":
+		return fmt.Errorf("Anthropic rate limit exceeded. Please try again later. (%s)", apiErr.Message)
+	case "overloaded_error", "api_error":
+		return fmt.Errorf("Anthropic service is temporarily unavailable. Please try again later. (%s)", apiErr.Message)
+	case "request_too_large":
+		return fmt.Errorf("Request is too large for Anthropic. Try a smaller screenshot or shorter question. (%s)", apiErr.Message)
+	default:
+		if statusCode == 0 {
+			return fmt.Errorf("Anthropic error (%s): %s", apiErr.Type, apiErr.Message)
+		}
+		return fmt.Errorf("API error (%d): %s", statusCode, apiErr.Message)
+	}
+}
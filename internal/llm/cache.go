@@ -0,0 +1,97 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"github.com/Kelen/Korner/internal/cache"
+)
+
+// cachedProvider answers repeated requests from the on-disk cache
+type cachedProvider struct {
+	Provider
+	cache  *cache.Cache
+	params string
+}
+
+// WithCache returns a provider that reuses earlier answers to identical
+// requests. params holds whatever else changes the answer, such as the
+// endpoint or answer length, and becomes part of the key. A nil cache
+// returns p unchanged.
+func WithCache(p Provider, c *cache.Cache, params string) Provider {
+	if c == nil {
+		return p
+	}
+	return &cachedProvider{Provider: p, cache: c, params: params}
+}
+
+// ContextWindow reports the window of the wrapped provider
+func (p *cachedProvider) ContextWindow() int { return ContextWindow(p.Provider) }
+
+func (p *cachedProvider) Query(ctx context.Context, req Request) (*Response, error) {
+	model := configuredModel(p)
+	if model == "" {
+		// The answer could come from whatever model the server loads next
+		return p.Provider.Query(ctx, req)
+	}
+	key := p.key(req, model)
+	if !cache.Bypassed(ctx) {
+		var resp Response
+		if p.cache.Get(key, &resp) {
+			log.Printf("[Cache] %s answer served from cache", p.Name())
+			if req.OnChunk != nil {
+				req.OnChunk(resp.Text)
+			}
+			resp.Cached = true
+			return &resp, nil
+		}
+	}
+
+	resp, err := p.Provider.Query(ctx, req)
+	if err != nil || resp == nil || resp.Text == "" {
+		return resp, err
+	}
+	// Tools such as capture_screen or web_search read the world as it is
+	// now, their answers go stale
+	if len(resp.ToolCalls) > 0 {
+		return resp, nil
+	}
+	if err := p.cache.Put(key, resp); err != nil {
+		log.Printf("[Cache] Warning: failed to store answer: %v", err)
+	}
+	return resp, nil
+}
+
+// key covers the prompt, screenshot, earlier turns, model and parameters
+func (p *cachedProvider) key(req Request, model string) string {
+	parts := []string{
+		"llm",
+		p.Name(),
+		model,
+		p.params,
+		req.Language,
+		req.Query,
+		imageKey(req.ImageBase64),
+		schemaKey(req.Schema),
+	}
+	for _, turn := range req.History {
+		parts = append(parts, turn.Question, turn.Answer, imageKey(turn.ImageBase64))
+	}
+	return cache.Key(parts...)
+}
+
+func schemaKey(schema *JSONSchema) string {
+	if schema == nil {
+		return ""
+	}
+	data, _ := json.Marshal(schema)
+	return string(data)
+}
+
+func imageKey(imageBase64 string) string {
+	if imageBase64 == "" {
+		return ""
+	}
+	return cache.HashBase64(imageBase64)
+}
@@ -0,0 +1,150 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Kelen/Korner/internal/cache"
+)
+
+func TestWithCache(t *testing.T) {
+	c, err := cache.New(t.TempDir(), 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake := &fakeProvider{name: "fake"}
+	p := WithCache(fake, c, "max=2048")
+	ctx := context.Background()
+	req := Request{Query: "what is this?", ImageBase64: "aW1hZ2U="}
+
+	first, err := p.Query(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var streamed string
+	req.OnChunk = func(chunk string) { streamed += chunk }
+	second, err := p.Query(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fake.calls != 1 {
+		t.Errorf("provider called %d times, want 1", fake.calls)
+	}
+	if second.Text != first.Text || second.Model != first.Model {
+		t.Errorf("cached answer %+v differs from %+v", second, first)
+	}
+	if streamed != first.Text {
+		t.Errorf("cached answer was not streamed, got %q", streamed)
+	}
+
+	req.ImageBase64 = "b3RoZXI="
+	if _, err := p.Query(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	if fake.calls != 2 {
+		t.Error("a different screenshot was answered from the cache")
+	}
+
+	if _, err := p.Query(cache.WithBypass(ctx), req); err != nil {
+		t.Fatal(err)
+	}
+	if fake.calls != 3 {
+		t.Error("bypass did not reach the provider")
+	}
+}
+
+// modelProvider is a fakeProvider configured with another model
+type modelProvider struct {
+	*fakeProvider
+	model string
+}
+
+func (p *modelProvider) Models() []string { return []string{p.model} }
+
+func TestWithCacheSeparatesModels(t *testing.T) {
+	c, err := cache.New(t.TempDir(), 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake := &fakeProvider{name: "gemini"}
+	flash := WithCache(&modelProvider{fake, "gemini-2.0-flash"}, c, "")
+	pro := WithCache(&modelProvider{fake, "gemini-2.5-pro"}, c, "")
+	req := Request{Query: "what is this?"}
+
+	for _, p := range []Provider{flash, pro, flash} {
+		if _, err := p.Query(context.Background(), req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if fake.calls != 2 {
+		t.Errorf("provider called %d times, want once per model", fake.calls)
+	}
+}
+
+// toolAnswerProvider answers after calling a tool
+type toolAnswerProvider struct{ *fakeProvider }
+
+func (p *toolAnswerProvider) Query(ctx context.Context, req Request) (*Response, error) {
+	resp, err := p.fakeProvider.Query(ctx, req)
+	if resp != nil {
+		resp.ToolCalls = []ToolCall{{Name: "capture_screen", Result: "text on screen"}}
+	}
+	return resp, err
+}
+
+func TestWithCacheSkipsToolAnswers(t *testing.T) {
+	c, err := cache.New(t.TempDir(), 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake := &fakeProvider{name: "fake"}
+	p := WithCache(&toolAnswerProvider{fake}, c, "")
+	req := Request{Query: "what is on my screen now?"}
+
+	for i := 0; i < 2; i++ {
+		if _, err := p.Query(context.Background(), req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if fake.calls != 2 {
+		t.Errorf("provider called %d times, answers that used tools should not be cached", fake.calls)
+	}
+}
+
+func TestWithCacheServedModel(t *testing.T) {
+	served := "model-a"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"data":[{"id":%q}]}`, served)
+	}))
+	defer server.Close()
+	defer InvalidateModelCache(server.URL + "/v1")
+
+	gptoss, _ := NewProvider("gptoss", Config{Endpoint: server.URL})
+	if got := configuredModel(gptoss); got != "model-a" {
+		t.Errorf("gptoss model = %q, want the served model-a", got)
+	}
+	served = "model-b"
+	InvalidateModelCache(server.URL + "/v1")
+	if got := configuredModel(gptoss); got != "model-b" {
+		t.Errorf("gptoss model = %q after the server switched, want model-b", got)
+	}
+
+	// Without a known model the answer is not cached
+	c, err := cache.New(t.TempDir(), 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake := &fakeProvider{name: "openai"}
+	p := WithCache(&modelProvider{fake, ""}, c, "")
+	for i := 0; i < 2; i++ {
+		if _, err := p.Query(context.Background(), Request{Query: "q"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if fake.calls != 2 {
+		t.Errorf("provider called %d times, answers of an unknown model should not be cached", fake.calls)
+	}
+}
@@ -0,0 +1,68 @@
+package llm
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultCompareConcurrency bounds how many providers Compare queries at once
+const DefaultCompareConcurrency = 3
+
+// Answer is the outcome of one provider in a comparison
+type Answer struct {
+	Provider Provider
+	Response *Response // Nil when Err is set
+	Err      error
+	Latency  time.Duration
+}
+
+// Compare sends the same question to every provider, at most limit at a
+// time, and returns the answers in the order of providers. build makes the
+// request for each provider and onDone, when not nil, is called from the
+// provider's goroutine as soon as its answer is in. Unlike
+// QueryWithFallback a failing provider does not stop the others.
+func Compare(ctx context.Context, providers []Provider, limit int, build func(Provider) (Request, error), onDone func(int, Answer)) []Answer {
+	if limit <= 0 {
+		limit = DefaultCompareConcurrency
+	}
+
+	answers := make([]Answer, len(providers))
+	slots := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for i, provider := range providers {
+		wg.Add(1)
+		go func(i int, provider Provider) {
+			defer wg.Done()
+			answer := Answer{Provider: provider}
+
+			select {
+			case slots <- struct{}{}:
+				answer.Response, answer.Latency, answer.Err = compareOne(ctx, provider, build)
+				<-slots
+			case <-ctx.Done():
+				answer.Err = ctx.Err()
+			}
+
+			answers[i] = answer
+			if onDone != nil {
+				onDone(i, answer)
+			}
+		}(i, provider)
+	}
+	wg.Wait()
+	return answers
+}
+
+func compareOne(ctx context.Context, provider Provider, build func(Provider) (Request, error)) (*Response, time.Duration, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, 0, err // Cancelled while waiting for a slot
+	}
+	req, err := build(provider)
+	if err != nil {
+		return nil, 0, err
+	}
+	start := time.Now()
+	resp, err := provider.Query(ctx, req)
+	return resp, time.Since(start), err
+}
@@ -0,0 +1,86 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// slowProvider answers after a pause and tracks how many queries overlap
+type slowProvider struct {
+	fakeProvider
+	running, peak *int32
+}
+
+func (s *slowProvider) Query(ctx context.Context, req Request) (*Response, error) {
+	n := atomic.AddInt32(s.running, 1)
+	defer atomic.AddInt32(s.running, -1)
+	for {
+		peak := atomic.LoadInt32(s.peak)
+		if n <= peak || atomic.CompareAndSwapInt32(s.peak, peak, n) {
+			break
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+	if s.name == "broken" {
+		return nil, errors.New("API error (500): down")
+	}
+	return &Response{Text: "answer from " + s.name, Model: s.name + "-model"}, nil
+}
+
+func TestCompare(t *testing.T) {
+	var running, peak int32
+	var providers []Provider
+	for _, name := range []string{"a", "b", "broken", "c", "d"} {
+		providers = append(providers, &slowProvider{fakeProvider: fakeProvider{name: name}, running: &running, peak: &peak})
+	}
+
+	var mu sync.Mutex
+	done := make(map[int]bool)
+	answers := Compare(context.Background(), providers, 2, buildPlain, func(i int, a Answer) {
+		mu.Lock()
+		done[i] = true
+		mu.Unlock()
+	})
+
+	if peak > 2 {
+		t.Errorf("%d queries ran at once, limit is 2", peak)
+	}
+	if len(done) != len(providers) {
+		t.Errorf("onDone called for %d of %d providers", len(done), len(providers))
+	}
+	for i, answer := range answers {
+		name := providers[i].Name()
+		if name == "broken" {
+			if answer.Err == nil {
+				t.Error("failing provider reported no error")
+			}
+			continue
+		}
+		if answer.Err != nil || answer.Response.Text != "answer from "+name {
+			t.Errorf("answer %d = %+v, want the answer from %s", i, answer, name)
+		}
+		if answer.Latency <= 0 {
+			t.Errorf("answer %d has no latency", i)
+		}
+	}
+}
+
+func TestCompareCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	providers := []Provider{&fakeProvider{name: "a"}, &fakeProvider{name: "b"}}
+	for _, answer := range Compare(ctx, providers, 1, buildPlain, nil) {
+		if !errors.Is(answer.Err, context.Canceled) {
+			t.Errorf("answer %+v, want context.Canceled", answer)
+		}
+	}
+	for _, p := range providers {
+		if calls := p.(*fakeProvider).calls; calls != 0 {
+			t.Errorf("%s queried %d times after cancel", p.Name(), calls)
+		}
+	}
+}
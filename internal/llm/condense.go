@@ -0,0 +1,138 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/Kelen/Korner/internal/prompts"
+	"github.com/Kelen/Korner/internal/tokens"
+)
+
+// promptOverhead reserves room for the system prompt and answering rules
+const promptOverhead = 400
+
+// maxCondenseRounds bounds how often summaries are summarized again
+const maxCondenseRounds = 3
+
+// ContextWindower is implemented by providers whose usable context differs
+// from what their model name suggests
+type ContextWindower interface {
+	ContextWindow() int
+}
+
+// ContextWindow returns how many tokens p can take in one request
+func ContextWindow(p Provider) int {
+	if cw, ok := p.(ContextWindower); ok {
+		return cw.ContextWindow()
+	}
+	if model := configuredModel(p); model != "" {
+		return tokens.ContextWindow(model)
+	}
+	return tokens.DefaultContextWindow
+}
+
+// InputBudget returns how many tokens of question and context fit in one
+// request to p when maxTokens are kept free for the answer
+func InputBudget(p Provider, maxTokens int) int {
+	if maxTokens <= 0 {
+		maxTokens = defaultMaxTokens
+	}
+	window := ContextWindow(p)
+	// Small local windows still need room for a question
+	if maxTokens > window/2 {
+		maxTokens = window / 2
+	}
+	return window - maxTokens - promptOverhead
+}
+
+// EstimateRequest approximates the prompt tokens of r, including the
+// system prompt and earlier turns
+func EstimateRequest(r Request) int {
+	total := promptOverhead + tokens.Estimate(r.Query)
+	for _, turn := range r.History {
+		total += tokens.Estimate(turn.Question) + tokens.Estimate(turn.Answer)
+		if turn.ImageBase64 != "" {
+			total += imageTokenCost
+		}
+	}
+	if r.ImageBase64 != "" {
+		total += imageTokenCost
+	}
+	return total
+}
+
+// QueryBudget returns how many tokens r.Query may take on p once the system
+// prompt, earlier turns and screenshot are accounted for
+func QueryBudget(p Provider, r Request, maxTokens int) int {
+	r.Query = ""
+	return InputBudget(p, maxTokens) - (EstimateRequest(r) - promptOverhead)
+}
+
+// Condensed describes a text that was summarized to fit the context window
+type Condensed struct {
+	Text           string
+	OriginalTokens int
+	Tokens         int
+	Chunks         int  // How many pieces the original was split into
+	Truncated      bool // Summaries still did not fit and were cut
+}
+
+// Condense shrinks text below budget tokens map-reduce style: the text is
+// split into chunks, p summarizes each one, and the joined summaries are
+// condensed again while they are still too long. query keeps the summaries
+// focused on what the user asked.
+func Condense(ctx context.Context, p Provider, text string, query string, budget int, language string) (*Condensed, error) {
+	result := &Condensed{Text: text, OriginalTokens: tokens.Estimate(text)}
+	result.Tokens = result.OriginalTokens
+	if budget <= 0 || result.Tokens <= budget {
+		return result, nil
+	}
+
+	// Each chunk has to fit into a summarization request of its own
+	chunkBudget := InputBudget(p, budget/2)
+	if chunkBudget < 256 {
+		chunkBudget = 256
+	}
+
+	for round := 0; round < maxCondenseRounds && result.Tokens > budget; round++ {
+		chunks := tokens.Split(result.Text, chunkBudget)
+		if round == 0 {
+			result.Chunks = len(chunks)
+		}
+		log.Printf("[Condense] Round %d: %d tokens in %d chunks, budget %d", round+1, result.Tokens, len(chunks), budget)
+
+		summaries := make([]string, 0, len(chunks))
+		for i, chunk := range chunks {
+			data := prompts.NewData(language)
+			data.Query = query
+			data.Text = chunk
+			resp, err := p.Query(ctx, Request{
+				Query:    prompts.Render(prompts.Condense, data),
+				Language: language,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("condense chunk %d/%d: %w", i+1, len(chunks), err)
+			}
+			summaries = append(summaries, strings.TrimSpace(resp.Text))
+		}
+
+		condensed := strings.Join(summaries, "\n\n")
+		condensedTokens := tokens.Estimate(condensed)
+		if condensedTokens >= result.Tokens {
+			// The model is not shortening anything, stop before looping forever
+			break
+		}
+		result.Text = condensed
+		result.Tokens = condensedTokens
+	}
+
+	if result.Tokens > budget {
+		result.Text = tokens.Truncate(result.Text, budget)
+		result.Tokens = tokens.Estimate(result.Text)
+		result.Truncated = true
+	}
+	log.Printf("[Condense] %d tokens condensed to %d", result.OriginalTokens, result.Tokens)
+	return result, nil
+}
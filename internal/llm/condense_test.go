@@ -0,0 +1,44 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/Kelen/Korner/internal/cache"
+	"github.com/Kelen/Korner/internal/metrics"
+)
+
+func TestContextWindowWrapped(t *testing.T) {
+	c, err := cache.New(t.TempDir(), 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := metrics.Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	echo := Tool{Name: "echo", Run: func(ctx context.Context, args json.RawMessage) (string, error) { return "", nil }}
+
+	base, err := NewProvider("ollama", Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrapped := WithMetrics(WithCache(WithTools(base, []Tool{echo}, 2), c, ""), store)
+	if got := ContextWindow(wrapped); got != ollamaContextWindow {
+		t.Errorf("wrapped Ollama window = %d, want %d", got, ollamaContextWindow)
+	}
+	if budget := InputBudget(wrapped, 2048); budget <= 0 {
+		t.Errorf("wrapped Ollama input budget = %d, want room for a question", budget)
+	}
+}
+
+func TestContextWindowConfiguredModel(t *testing.T) {
+	p, err := NewProvider("gemini", Config{Model: "gemini-pro"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ContextWindow(p); got != 32768 {
+		t.Errorf("window = %d, want the configured gemini-pro's 32768", got)
+	}
+}
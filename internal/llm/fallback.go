@@ -0,0 +1,274 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RetryPolicy controls how often a provider is retried before the chain
+// moves on to the next one
+type RetryPolicy struct {
+	MaxAttempts int           // Attempts per provider, including the first
+	BaseDelay   time.Duration // Delay before the first retry, doubled each time
+	MaxDelay    time.Duration // Upper bound for the backoff delay
+}
+
+// DefaultRetryPolicy retries a failing provider once after a short pause
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 2,
+	BaseDelay:   500 * time.Millisecond,
+	MaxDelay:    4 * time.Second,
+}
+
+// backoff returns the delay before retry number attempt (1-based)
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay << (attempt - 1)
+	if delay <= 0 || delay > p.MaxDelay {
+		return p.MaxDelay
+	}
+	return delay
+}
+
+const (
+	// breakerThreshold is the number of consecutive failures that open a breaker
+	breakerThreshold = 3
+	// breakerCooldown is how long an open breaker skips its provider
+	breakerCooldown = 30 * time.Second
+)
+
+// circuitBreaker tracks consecutive failures of one provider. While open the
+// provider is skipped; after the cooldown a single trial request is let
+// through and its result closes or reopens the breaker.
+type circuitBreaker struct {
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time
+	trial    bool // A half-open trial request is in flight
+}
+
+var (
+	breakers      = make(map[string]*circuitBreaker)
+	breakersMutex sync.Mutex
+)
+
+// breakerFor returns the shared breaker for the named provider
+func breakerFor(name string) *circuitBreaker {
+	breakersMutex.Lock()
+	defer breakersMutex.Unlock()
+
+	b, ok := breakers[name]
+	if !ok {
+		b = &circuitBreaker{}
+		breakers[name] = b
+	}
+	return b
+}
+
+// allow reports whether a request may be sent to the provider, and whether
+// that request is the half-open trial, which must end with success, failure
+// or endTrial
+func (b *circuitBreaker) allow() (allowed bool, trial bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < breakerThreshold {
+		return true, false
+	}
+	if time.Since(b.openedAt) < breakerCooldown || b.trial {
+		return false, false
+	}
+	b.trial = true
+	return true, true
+}
+
+// endTrial gives up the trial without a verdict, for a request that was
+// cancelled or never sent, so the next request can try instead
+func (b *circuitBreaker) endTrial() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trial = false
+}
+
+func (b *circuitBreaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	b.trial = false
+}
+
+func (b *circuitBreaker) failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.trial = false
+	if b.failures >= breakerThreshold {
+		b.openedAt = time.Now()
+	}
+}
+
+// ResetBreakers closes every circuit breaker, for example after the
+// endpoints were changed in settings
+func ResetBreakers() {
+	breakersMutex.Lock()
+	defer breakersMutex.Unlock()
+
+	breakers = make(map[string]*circuitBreaker)
+}
+
+// StatusError is an error answer from a provider's HTTP API. Err holds the
+// message shown to the user, StatusCode lets the chain tell an overloaded
+// backend from a bad request.
+type StatusError struct {
+	StatusCode int
+	Err        error
+}
+
+func (e *StatusError) Error() string { return e.Err.Error() }
+
+func (e *StatusError) Unwrap() error { return e.Err }
+
+// HTTPStatus returns the status code, errors of other packages such as
+// ocr.StatusError report theirs through the same method
+func (e *StatusError) HTTPStatus() int { return e.StatusCode }
+
+// apiError returns the StatusError for a non-OK answer with body
+func apiError(statusCode int, body []byte) error {
+	return &StatusError{
+		StatusCode: statusCode,
+		Err:        fmt.Errorf("API error (%d): %s", statusCode, strings.TrimSpace(string(body))),
+	}
+}
+
+// isTransient reports whether err looks like the backend being down or
+// overloaded rather than a problem with the request or the settings
+func isTransient(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	var status interface{ HTTPStatus() int }
+	if errors.As(err, &status) {
+		code := status.HTTPStatus()
+		return code == http.StatusTooManyRequests || code >= 500
+	}
+	return false
+}
+
+// BuildFunc prepares the request for one provider of a chain, so image
+// handling can follow that provider's capabilities
+type BuildFunc func(p Provider) (Request, error)
+
+// QueryWithFallback asks each provider in order until one answers. Transient
+// failures are retried with exponential backoff, and providers whose circuit
+// breaker is open are skipped. It returns the answer and the provider that
+// served it. Once a streamed answer has started the chain does not switch
+// providers, so the user never sees two answers mixed together.
+func QueryWithFallback(ctx context.Context, providers []Provider, policy RetryPolicy, build BuildFunc) (*Response, Provider, error) {
+	if len(providers) == 0 {
+		return nil, nil, errors.New("no provider configured")
+	}
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = 1
+	}
+
+	var errs []string
+	var lastErr error
+	for _, p := range providers {
+		breaker := breakerFor(p.Name())
+		allowed, trial := breaker.allow()
+		if !allowed {
+			log.Printf("[Fallback] Skipping %s, circuit open", p.Name())
+			errs = append(errs, fmt.Sprintf("%s: temporarily disabled after repeated failures", p.Name()))
+			continue
+		}
+
+		resp, final, err := queryProvider(ctx, p, breaker, trial, policy, build)
+		if err == nil {
+			if len(errs) > 0 {
+				log.Printf("[Fallback] Served by %s", p.Name())
+			}
+			return resp, p, nil
+		}
+		if final {
+			return nil, p, err
+		}
+		log.Printf("[Fallback] %s failed: %v", p.Name(), err)
+		lastErr = err
+		errs = append(errs, fmt.Sprintf("%s: %v", p.Name(), err))
+	}
+
+	if len(providers) == 1 && lastErr != nil {
+		// Without fallbacks keep the provider's own error message
+		return nil, nil, lastErr
+	}
+	return nil, nil, fmt.Errorf("all providers failed:\n%s", strings.Join(errs, "\n"))
+}
+
+// queryProvider asks one provider of the chain, retrying transient failures,
+// and records the outcome on its breaker. final reports an error the chain
+// must not move on from: a failed build, a cancelled request or a stream
+// that already reached the caller. Those end a trial without a verdict.
+func queryProvider(ctx context.Context, p Provider, breaker *circuitBreaker, trial bool, policy RetryPolicy, build BuildFunc) (resp *Response, final bool, err error) {
+	if trial {
+		defer breaker.endTrial()
+	}
+
+	req, err := build(p)
+	if err != nil {
+		return nil, true, err
+	}
+
+	// Track whether any chunk reached the caller
+	streamed := false
+	if onChunk := req.OnChunk; onChunk != nil {
+		req.OnChunk = func(chunk string) {
+			streamed = true
+			onChunk(chunk)
+		}
+	}
+
+	for attempt := 1; attempt <= policy.MaxAttempts; attempt++ {
+		resp, err = p.Query(ctx, req)
+		if err == nil {
+			breaker.success()
+			if attempt > 1 {
+				log.Printf("[Fallback] %s answered on attempt %d", p.Name(), attempt)
+			}
+			return resp, true, nil
+		}
+
+		if ctx.Err() != nil || streamed {
+			return nil, true, err
+		}
+		if !isTransient(err) {
+			break
+		}
+		if attempt < policy.MaxAttempts {
+			delay := policy.backoff(attempt)
+			log.Printf("[Fallback] %s failed (attempt %d/%d), retrying in %v: %v", p.Name(), attempt, policy.MaxAttempts, delay, err)
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return nil, true, ctx.Err()
+			}
+		}
+	}
+
+	if isTransient(err) {
+		breaker.failure()
+	} else {
+		// The backend answered, only this request or its settings are wrong
+		breaker.success()
+	}
+	return nil, false, err
+}
@@ -0,0 +1,168 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeProvider answers with the queued errors first, then with its name
+type fakeProvider struct {
+	name   string
+	errs   []error
+	chunks []string
+	calls  int
+}
+
+func (f *fakeProvider) Name() string               { return f.name }
+func (f *fakeProvider) Capabilities() Capabilities { return Capabilities{Streaming: true} }
+func (f *fakeProvider) Models() []string           { return []string{f.name + "-model"} }
+
+func (f *fakeProvider) Query(ctx context.Context, req Request) (*Response, error) {
+	f.calls++
+	for _, chunk := range f.chunks {
+		if req.OnChunk != nil {
+			req.OnChunk(chunk)
+		}
+	}
+	if len(f.errs) > 0 {
+		err := f.errs[0]
+		f.errs = f.errs[1:]
+		return nil, err
+	}
+	return &Response{Text: "answer from " + f.name, Model: f.name + "-model"}, nil
+}
+
+var testPolicy = RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}
+
+func buildPlain(p Provider) (Request, error) {
+	return Request{Query: "hi"}, nil
+}
+
+func TestQueryWithFallback(t *testing.T) {
+	ResetBreakers()
+	down := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+
+	primary := &fakeProvider{name: "primary", errs: []error{down, down}}
+	backup := &fakeProvider{name: "backup"}
+
+	resp, served, err := QueryWithFallback(context.Background(), []Provider{primary, backup}, testPolicy, buildPlain)
+	if err != nil {
+		t.Fatalf("QueryWithFallback: %v", err)
+	}
+	if served.Name() != "backup" || resp.Model != "backup-model" {
+		t.Errorf("served by %s (%s), want backup", served.Name(), resp.Model)
+	}
+	if primary.calls != 2 {
+		t.Errorf("primary called %d times, want 2 (one retry)", primary.calls)
+	}
+}
+
+func TestQueryWithFallbackNonTransient(t *testing.T) {
+	ResetBreakers()
+	primary := &fakeProvider{name: "primary", errs: []error{&StatusError{StatusCode: 401, Err: errors.New("API error (401): bad key")}}}
+	backup := &fakeProvider{name: "backup"}
+
+	_, served, err := QueryWithFallback(context.Background(), []Provider{primary, backup}, testPolicy, buildPlain)
+	if err != nil || served.Name() != "backup" {
+		t.Fatalf("served = %v, err = %v, want backup", served, err)
+	}
+	if primary.calls != 1 {
+		t.Errorf("primary called %d times, want no retry for a 401", primary.calls)
+	}
+}
+
+func TestQueryWithFallbackBreaker(t *testing.T) {
+	ResetBreakers()
+	down := &StatusError{StatusCode: 503, Err: errors.New("API error (503): overloaded")}
+
+	for i := 0; i < breakerThreshold; i++ {
+		primary := &fakeProvider{name: "primary", errs: []error{down, down}}
+		QueryWithFallback(context.Background(), []Provider{primary}, testPolicy, buildPlain)
+	}
+
+	primary := &fakeProvider{name: "primary"}
+	backup := &fakeProvider{name: "backup"}
+	_, served, err := QueryWithFallback(context.Background(), []Provider{primary, backup}, testPolicy, buildPlain)
+	if err != nil || served.Name() != "backup" {
+		t.Fatalf("served = %v, err = %v, want backup", served, err)
+	}
+	if primary.calls != 0 {
+		t.Errorf("primary called %d times while its breaker is open", primary.calls)
+	}
+}
+
+func TestQueryWithFallbackAfterStream(t *testing.T) {
+	ResetBreakers()
+	primary := &fakeProvider{
+		name:   "primary",
+		chunks: []string{"partial"},
+		errs:   []error{&net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset")}},
+	}
+	backup := &fakeProvider{name: "backup"}
+
+	build := func(p Provider) (Request, error) {
+		return Request{Query: "hi", OnChunk: func(string) {}}, nil
+	}
+	if _, _, err := QueryWithFallback(context.Background(), []Provider{primary, backup}, testPolicy, build); err == nil {
+		t.Fatal("expected the error once streaming had started")
+	}
+	if backup.calls != 0 {
+		t.Errorf("backup called %d times after the answer started streaming", backup.calls)
+	}
+}
+
+func TestQueryWithFallbackCancelledTrial(t *testing.T) {
+	ResetBreakers()
+	breaker := breakerFor("primary")
+	breaker.failures = breakerThreshold
+	breaker.openedAt = time.Now().Add(-breakerCooldown)
+
+	// The half-open trial is cancelled before it gets an answer
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	primary := &fakeProvider{name: "primary", errs: []error{context.Canceled}}
+	QueryWithFallback(ctx, []Provider{primary}, testPolicy, buildPlain)
+
+	primary = &fakeProvider{name: "primary"}
+	_, served, err := QueryWithFallback(context.Background(), []Provider{primary}, testPolicy, buildPlain)
+	if err != nil || served.Name() != "primary" {
+		t.Fatalf("served = %v, err = %v, want the next request to get the trial", served, err)
+	}
+	if breaker.failures != 0 {
+		t.Errorf("failures = %d, want the breaker closed after the trial answered", breaker.failures)
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	for _, tt := range []struct {
+		status int
+		body   string
+		want   bool
+	}{
+		{http.StatusTooManyRequests, `{"error":{"code":429,"message":"Resource has been exhausted","status":"RESOURCE_EXHAUSTED"}}`, true},
+		{http.StatusServiceUnavailable, `upstream down`, true},
+		{http.StatusBadRequest, `{"error":{"code":400,"message":"API key not valid.","status":"INVALID_ARGUMENT"}}`, false},
+	} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tt.status)
+			w.Write([]byte(tt.body))
+		}))
+		_, err := queryGemini(context.Background(), Config{Endpoint: server.URL, APIKey: "k"}, Request{Query: "hi"})
+		server.Close()
+		if got := isTransient(err); got != tt.want {
+			t.Errorf("isTransient(%v) = %v, want %v", err, got, tt.want)
+		}
+	}
+
+	// Rate limits reported in the middle of an Anthropic stream
+	stream := "event: error\ndata: {\"type\":\"error\",\"error\":{\"type\":\"rate_limit_error\",\"message\":\"slow down\"}}\n\n"
+	if _, _, err := readAnthropicStream(strings.NewReader(stream), func(string) {}); !isTransient(err) {
+		t.Errorf("isTransient(%v) = false for an Anthropic rate limit", err)
+	}
+}
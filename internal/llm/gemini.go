@@ -1,11 +1,212 @@
 package llm
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
 )
 
-// QueryGemini calls Google's Gemini API (DISABLED)
+const (
+	// DefaultGeminiEndpoint is the public Gemini API base URL
+	DefaultGeminiEndpoint = "https://generativelanguage.googleapis.com/v1beta"
+	// DefaultGeminiModel is the Gemini model used when none is configured
+	DefaultGeminiModel = "gemini-2.0-flash-lite"
+)
+
+func init() {
+	Register("gemini", func(cfg Config) Provider {
+		return &geminiProvider{cfg: cfg}
+	})
+}
+
+// geminiProvider adapts the Gemini API to the Provider interface
+type geminiProvider struct {
+	cfg Config
+}
+
+func (p *geminiProvider) Name() string { return "gemini" }
+
+func (p *geminiProvider) Capabilities() Capabilities {
+	return Capabilities{Vision: true}
+}
+
+// Models lists the configured model, which is what Query sends
+func (p *geminiProvider) Models() []string {
+	if p.cfg.Model != "" {
+		return []string{p.cfg.Model}
+	}
+	return []string{DefaultGeminiModel}
+}
+
+func (p *geminiProvider) Query(ctx context.Context, req Request) (*Response, error) {
+	return queryGemini(ctx, p.cfg, req)
+}
+
+// QueryGemini calls Google's Gemini generateContent API
 func QueryGemini(ctx context.Context, query string, screenshotBase64 string, apiKey string, language string) (string, error) {
-	return "", fmt.Errorf("Gemini API is disabled. Please use GPT-OSS-120B instead")
+	resp, err := queryGemini(ctx, Config{APIKey: apiKey}, Request{
+		Query:       query,
+		ImageBase64: screenshotBase64,
+		Language:    language,
+	})
+	if err != nil {
+		return "", err
+	}
+	return resp.Text, nil
+}
+
+// queryGemini sends the request to {endpoint}/models/{model}:generateContent
+func queryGemini(ctx context.Context, cfg Config, r Request) (*Response, error) {
+	if cfg.APIKey == "" {
+		return nil, errors.New("API key not configured. Please set your API key in Settings.")
+	}
+
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = DefaultGeminiEndpoint
+	}
+	model := cfg.Model
+	if model == "" {
+		model = DefaultGeminiModel
+	}
+	apiURL := fmt.Sprintf("%s/models/%s:generateContent", strings.TrimSuffix(endpoint, "/"), url.PathEscape(model))
+	log.Printf("[Gemini] Using endpoint: %s", apiURL)
+
+	// Earlier thread turns alternate user and model roles
+	contents := make([]GeminiContent, 0, len(r.History)*2+1)
+	for _, turn := range r.History {
+		contents = append(contents,
+			geminiUserContent(turn.Question, turn.ImageBase64),
+			GeminiContent{Role: "model", Parts: []GeminiPart{{Text: turn.Answer}}},
+		)
+	}
+	contents = append(contents, geminiUserContent(r.Query, r.ImageBase64))
+	if r.ImageBase64 != "" {
+		log.Printf("[Gemini] Added image to request (base64 length: %d)", len(r.ImageBase64))
+	}
+
+	maxTokens := cfg.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = defaultMaxTokens
+	}
+
+	reqPayload := GeminiRequest{
+		Contents: contents,
+		SystemInstruction: &GeminiContent{
+			Parts: []GeminiPart{{Text: defaultSystemPrompt(r.Language, r.Query)}},
+		},
+		GenerationConfig: &GeminiGenerationConfig{
+			Temperature:     0.7,
+			MaxOutputTokens: maxTokens,
+		},
+	}
+
+	body, err := json.Marshal(reqPayload)
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+
+	httpClient := &http.Client{Timeout: 120 * time.Second}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-goog-api-key", cfg.APIKey)
+
+	log.Printf("[Gemini] Sending request with model: %s", model)
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("http request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		log.Printf("[Gemini] ERROR response: %s", string(bodyBytes))
+		return nil, geminiError(resp.StatusCode, bodyBytes)
+	}
+
+	var result GeminiResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+
+	if result.PromptFeedback != nil && result.PromptFeedback.BlockReason != "" {
+		return nil, fmt.Errorf("Gemini blocked the request (%s). Please rephrase your question.", result.PromptFeedback.BlockReason)
+	}
+	if len(result.Candidates) == 0 {
+		return nil, errors.New("no response from API")
+	}
+
+	var text strings.Builder
+	for _, part := range result.Candidates[0].Content.Parts {
+		text.WriteString(part.Text)
+	}
+	responseText := strings.TrimSpace(text.String())
+	if responseText == "" {
+		if reason := result.Candidates[0].FinishReason; reason != "" && reason != "STOP" {
+			return nil, fmt.Errorf("Gemini stopped without an answer (%s)", reason)
+		}
+		return nil, errors.New("empty response from API")
+	}
+
+	log.Printf("[Gemini] Success! Response length: %d", len(responseText))
+	usage := Usage{PromptTokens: result.UsageMetadata.PromptTokenCount, CompletionTokens: result.UsageMetadata.CandidatesTokenCount}
+	return &Response{Text: responseText, Model: model, Usage: usage}, nil
+}
+
+// geminiUserContent builds a user turn with an optional inline image
+func geminiUserContent(text string, imageBase64 string) GeminiContent {
+	content := GeminiContent{
+		Role:  "user",
+		Parts: []GeminiPart{{Text: text}},
+	}
+	if imageBase64 != "" {
+		mimeType, data := splitDataURL(imageBase64)
+		content.Parts = append(content.Parts, GeminiPart{
+			InlineData: &GeminiInlineData{
+				MimeType: mimeType,
+				Data:     data,
+			},
+		})
+	}
+	return content
+}
+
+// geminiError turns an API error response into a message the UI can show
+func geminiError(statusCode int, body []byte) error {
+	return &StatusError{StatusCode: statusCode, Err: geminiMessage(statusCode, body)}
+}
+
+// geminiMessage explains an API error response in words the user can act on
+func geminiMessage(statusCode int, body []byte) error {
+	var apiErr GeminiErrorResponse
+	message := strings.TrimSpace(string(body))
+	if err := json.Unmarshal(body, &apiErr); err == nil && apiErr.Error.Message != "" {
+		message = apiErr.Error.Message
+	}
+
+	switch {
+	case statusCode == http.StatusBadRequest && strings.Contains(message, "API key"),
+		statusCode == http.StatusUnauthorized,
+		statusCode == http.StatusForbidden:
+		return fmt.Errorf("Gemini API key is invalid or lacks permission. Please check your API key in Settings. (%s)", message)
+	case statusCode == http.StatusNotFound:
+		return fmt.Errorf("Gemini model or endpoint not found. Please check the endpoint in Settings. (%s)", message)
+	case statusCode == http.StatusTooManyRequests:
+		return fmt.Errorf("Gemini rate limit or quota exceeded. Please try again later. (%s)", message)
+	case statusCode >= 500:
+		return fmt.Errorf("Gemini service is temporarily unavailable (%d). Please try again later.", statusCode)
+	default:
+		return fmt.Errorf("API error (%d): %s", statusCode, message)
+	}
 }
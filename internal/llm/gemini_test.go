@@ -0,0 +1,98 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestQueryGemini(t *testing.T) {
+	var got GeminiRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/models/gemini-test:generateContent" {
+			t.Errorf("unexpected path %q", r.URL.Path)
+		}
+		if key := r.Header.Get("x-goog-api-key"); key != "test-key" {
+			t.Errorf("x-goog-api-key = %q, want %q", key, "test-key")
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		w.Write([]byte(`{"candidates":[{"content":{"role":"model","parts":[{"text":"Hello "},{"text":"there"}]},"finishReason":"STOP"}]}`))
+	}))
+	defer server.Close()
+
+	p, err := NewProvider("gemini", Config{Endpoint: server.URL, APIKey: "test-key", Model: "gemini-test", MaxTokens: 512})
+	if err != nil {
+		t.Fatalf("NewProvider: %v", err)
+	}
+
+	resp, err := p.Query(context.Background(), Request{
+		Query:       "What is this?",
+		ImageBase64: "data:image/jpeg;base64,AAAA",
+		Language:    "en",
+	})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if resp.Text != "Hello there" || resp.Model != "gemini-test" {
+		t.Errorf("response = %+v", resp)
+	}
+
+	if len(got.Contents) != 1 || len(got.Contents[0].Parts) != 2 {
+		t.Fatalf("unexpected contents: %+v", got.Contents)
+	}
+	inline := got.Contents[0].Parts[1].InlineData
+	if inline == nil || inline.MimeType != "image/jpeg" || inline.Data != "AAAA" {
+		t.Errorf("inline data = %+v", inline)
+	}
+	if got.GenerationConfig == nil || got.GenerationConfig.MaxOutputTokens != 512 {
+		t.Errorf("generation config = %+v, want the configured 512 tokens", got.GenerationConfig)
+	}
+}
+
+func TestQueryGeminiErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   string
+	}{
+		{
+			name:   "Invalid key",
+			status: http.StatusBadRequest,
+			body:   `{"error":{"code":400,"message":"API key not valid. Please pass a valid API key.","status":"INVALID_ARGUMENT"}}`,
+			want:   "API key is invalid",
+		},
+		{
+			name:   "Quota",
+			status: http.StatusTooManyRequests,
+			body:   `{"error":{"code":429,"message":"Resource has been exhausted","status":"RESOURCE_EXHAUSTED"}}`,
+			want:   "rate limit",
+		},
+		{
+			name:   "Server error",
+			status: http.StatusServiceUnavailable,
+			body:   `upstream down`,
+			want:   "temporarily unavailable",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			_, err := queryGemini(context.Background(), Config{Endpoint: server.URL, APIKey: "k"}, Request{Query: "hi"})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
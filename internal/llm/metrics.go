@@ -0,0 +1,68 @@
+package llm
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/Kelen/Korner/internal/metrics"
+	"github.com/Kelen/Korner/internal/tokens"
+)
+
+// meteredProvider records the latency and token usage of every query
+type meteredProvider struct {
+	Provider
+	store *metrics.Store
+}
+
+// WithMetrics returns a provider that records every query in store. A nil
+// store returns p unchanged.
+func WithMetrics(p Provider, store *metrics.Store) Provider {
+	if store == nil {
+		return p
+	}
+	return &meteredProvider{Provider: p, store: store}
+}
+
+// ContextWindow reports the window of the wrapped provider
+func (p *meteredProvider) ContextWindow() int { return ContextWindow(p.Provider) }
+
+func (p *meteredProvider) Query(ctx context.Context, req Request) (*Response, error) {
+	start := time.Now()
+	resp, err := p.Provider.Query(ctx, req)
+	if err != nil || resp == nil {
+		p.store.Observe(metrics.KindLLM, p.Name(), p.model(nil), start, err)
+		return resp, err
+	}
+
+	record := metrics.Record{
+		Time:      start,
+		Kind:      metrics.KindLLM,
+		Provider:  p.Name(),
+		Model:     p.model(resp),
+		LatencyMs: time.Since(start).Milliseconds(),
+		Cached:    resp.Cached,
+	}
+	if !resp.Cached {
+		record.PromptTokens = resp.Usage.PromptTokens
+		record.CompletionTokens = resp.Usage.CompletionTokens
+		if resp.Usage == (Usage{}) {
+			// Streams and tool loops often come without usage
+			record.PromptTokens = EstimateRequest(req)
+			record.CompletionTokens = tokens.Estimate(resp.Text)
+			record.Estimated = true
+		}
+	}
+	if err := p.store.Add(record); err != nil {
+		log.Printf("[Metrics] Warning: %v", err)
+	}
+	return resp, nil
+}
+
+// model returns the model that answered, or the configured one
+func (p *meteredProvider) model(resp *Response) string {
+	if resp != nil && resp.Model != "" {
+		return resp.Model
+	}
+	return configuredModel(p)
+}
@@ -0,0 +1,48 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Kelen/Korner/internal/cache"
+	"github.com/Kelen/Korner/internal/metrics"
+)
+
+func TestWithMetrics(t *testing.T) {
+	store, err := metrics.Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := cache.New(t.TempDir(), 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake := &fakeProvider{name: "fake", errs: []error{errors.New("API error (429): slow down")}}
+	p := WithMetrics(WithCache(fake, c, ""), store)
+	ctx := context.Background()
+	req := Request{Query: "what is this?"}
+
+	for i := 0; i < 3; i++ {
+		p.Query(ctx, req)
+	}
+
+	records, err := store.Load(time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("recorded %d queries, want 3", len(records))
+	}
+	failed, answered, cached := records[0], records[1], records[2]
+	if failed.ErrorClass != metrics.ErrorRateLimit || failed.Model != "fake-model" {
+		t.Errorf("failed query recorded as %+v", failed)
+	}
+	if !answered.Estimated || answered.PromptTokens == 0 || answered.CompletionTokens == 0 {
+		t.Errorf("answer without usage was not estimated: %+v", answered)
+	}
+	if !cached.Cached || cached.PromptTokens != 0 {
+		t.Errorf("cache hit recorded as %+v", cached)
+	}
+}
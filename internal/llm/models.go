@@ -0,0 +1,145 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// modelCacheTTL bounds how long a detected model name is trusted
+const modelCacheTTL = 10 * time.Minute
+
+// ModelLister is implemented by providers that can ask their server which
+// models are available
+type ModelLister interface {
+	ListModels(ctx context.Context) ([]string, error)
+}
+
+type modelCacheEntry struct {
+	name    string
+	fetched time.Time
+}
+
+var (
+	modelNameCache      = make(map[string]modelCacheEntry)
+	modelNameCacheMutex sync.RWMutex
+)
+
+// ModelsResponse represents the vLLM /v1/models response
+type ModelsResponse struct {
+	Data []ModelData `json:"data"`
+}
+
+type ModelData struct {
+	ID string `json:"id"`
+}
+
+// InvalidateModelCache forgets the detected model for baseURL, or for every
+// endpoint when baseURL is empty. Call it when endpoints change.
+func InvalidateModelCache(baseURL string) {
+	modelNameCacheMutex.Lock()
+	defer modelNameCacheMutex.Unlock()
+
+	if baseURL == "" {
+		modelNameCache = make(map[string]modelCacheEntry)
+		return
+	}
+	delete(modelNameCache, baseURL)
+}
+
+// getModelName fetches the actual model name from vLLM server
+func getModelName(baseURL string, apiKey string) (string, error) {
+	// Check cache first
+	modelNameCacheMutex.RLock()
+	cached, ok := modelNameCache[baseURL]
+	modelNameCacheMutex.RUnlock()
+	if ok && time.Since(cached.fetched) < modelCacheTTL {
+		return cached.name, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if _, err := listOpenAIModels(ctx, baseURL, apiKey); err != nil {
+		return "", err
+	}
+
+	modelNameCacheMutex.RLock()
+	modelName := modelNameCache[baseURL].name
+	modelNameCacheMutex.RUnlock()
+	log.Printf("[Models] Found model: %s", modelName)
+	return modelName, nil
+}
+
+// listOpenAIModels lists the models served at {baseURL}/models and refreshes
+// the model cache, dropping a cached model the server no longer serves
+func listOpenAIModels(ctx context.Context, baseURL string, apiKey string) ([]string, error) {
+	modelsURL := strings.TrimSuffix(baseURL, "/") + "/models"
+	log.Printf("[Models] Fetching available models from: %s", modelsURL)
+
+	httpClient := &http.Client{Timeout: 10 * time.Second}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, modelsURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create models request: %w", err)
+	}
+
+	if apiKey == "" {
+		apiKey = "dummy-key"
+	}
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch models failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("models API error (%d): %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	var modelsResp ModelsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&modelsResp); err != nil {
+		return nil, fmt.Errorf("decode models response: %w", err)
+	}
+
+	if len(modelsResp.Data) == 0 {
+		InvalidateModelCache(baseURL)
+		return nil, errors.New("no models available on server")
+	}
+
+	models := make([]string, 0, len(modelsResp.Data))
+	for _, m := range modelsResp.Data {
+		models = append(models, m.ID)
+	}
+
+	// Keep the cached model if the server still serves it, otherwise use the first one
+	modelNameCacheMutex.Lock()
+	entry, ok := modelNameCache[baseURL]
+	if !ok || !containsString(models, entry.name) {
+		entry.name = models[0]
+	}
+	entry.fetched = time.Now()
+	modelNameCache[baseURL] = entry
+	modelNameCacheMutex.Unlock()
+
+	return models, nil
+}
+
+// containsString reports whether list contains s
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,167 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Kelen/Korner/internal/ocr"
+)
+
+func init() {
+	Register("ollama", func(cfg Config) Provider {
+		return &ollamaProvider{cfg: cfg}
+	})
+}
+
+// ollamaProvider adapts the local Ollama server to the Provider interface
+type ollamaProvider struct {
+	cfg Config
+}
+
+func (p *ollamaProvider) Name() string { return "ollama" }
+
+// Capabilities reports vision support; the default qwen3-vl reads screenshots directly
+func (p *ollamaProvider) Capabilities() Capabilities {
+	return Capabilities{Vision: true, Streaming: true, Tools: true}
+}
+
+// ollamaContextWindow is Ollama's default num_ctx. Prompts beyond it are
+// cut silently by the server, whatever the model itself supports.
+const ollamaContextWindow = 4096
+
+// ContextWindow reports Ollama's default num_ctx rather than the model's limit
+func (p *ollamaProvider) ContextWindow() int {
+	return ollamaContextWindow
+}
+
+func (p *ollamaProvider) Models() []string {
+	return []string{p.model()}
+}
+
+// ListModels returns the models installed on the Ollama server
+func (p *ollamaProvider) ListModels(ctx context.Context) ([]string, error) {
+	return ocr.ListModels(ctx, p.cfg.Endpoint)
+}
+
+// model returns the configured model or the default vision model
+func (p *ollamaProvider) model() string {
+	if p.cfg.Model != "" {
+		return p.cfg.Model
+	}
+	return ocr.DefaultModel
+}
+
+func (p *ollamaProvider) Query(ctx context.Context, req Request) (*Response, error) {
+	if req.Schema != nil {
+		return p.queryJSON(ctx, req)
+	}
+
+	var text string
+	var usage ocr.Usage
+	var err error
+	if len(req.History) > 0 {
+		// Threads go through /api/chat so earlier turns keep their roles
+		text, usage, err = ocr.ChatOllama(ctx, ollamaMessages(req), p.cfg.Endpoint, p.model(), req.Language, req.OnChunk)
+	} else {
+		text, usage, err = ocr.QueryOllamaStream(ctx, req.Query, req.ImageBase64, p.cfg.Endpoint, p.model(), req.Language, req.OnChunk)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Thinking models such as Qwen and DeepSeek inline their reasoning in tags
+	parsed := ParseResponse(text)
+	return &Response{Text: cleanResponseText(text), Model: p.model(), Reasoning: parsed.Reasoning, Usage: ollamaUsage(usage)}, nil
+}
+
+// queryJSON asks for an answer constrained to req.Schema through the
+// format field of /api/chat
+func (p *ollamaProvider) queryJSON(ctx context.Context, req Request) (*Response, error) {
+	messages := append([]ocr.OllamaChatMessage{
+		{Role: "system", Content: defaultSystemPrompt(req.Language, req.Query)},
+	}, ollamaMessages(req)...)
+
+	text, usage, err := ocr.ChatOllamaJSON(ctx, messages, req.Schema.Schema, p.cfg.Endpoint, p.model())
+	if err != nil {
+		return nil, err
+	}
+	return &Response{Text: text, Model: p.model(), Usage: ollamaUsage(usage)}, nil
+}
+
+// ollamaUsage converts the eval counts Ollama reports
+func ollamaUsage(u ocr.Usage) Usage {
+	return Usage{PromptTokens: u.PromptTokens, CompletionTokens: u.CompletionTokens}
+}
+
+// QueryWithTools runs one step of the tool loop through /api/chat
+func (p *ollamaProvider) QueryWithTools(ctx context.Context, req Request, tools []Tool, rounds []ToolRound, allowCalls bool) (*ToolReply, error) {
+	messages := append([]ocr.OllamaChatMessage{
+		{Role: "system", Content: defaultSystemPrompt(req.Language, req.Query)},
+	}, ollamaMessages(req)...)
+
+	for _, round := range rounds {
+		assistant := ocr.OllamaChatMessage{Role: "assistant", Content: round.Text}
+		for _, call := range round.Calls {
+			var tc ocr.OllamaToolCall
+			tc.Function.Name = call.Name
+			tc.Function.Arguments = []byte(call.Arguments)
+			assistant.ToolCalls = append(assistant.ToolCalls, tc)
+		}
+		messages = append(messages, assistant)
+		for _, call := range round.Calls {
+			messages = append(messages, ocr.OllamaChatMessage{Role: "tool", ToolName: call.Name, Content: toolOutput(call)})
+		}
+	}
+
+	// Ollama has no tool_choice, leaving the tools out forces an answer
+	var ollamaTools []ocr.OllamaTool
+	if allowCalls {
+		for _, tool := range tools {
+			ollamaTools = append(ollamaTools, ocr.OllamaTool{
+				Type: "function",
+				Function: ocr.OllamaToolFunction{
+					Name:        tool.Name,
+					Description: tool.Description,
+					Parameters:  tool.Parameters,
+				},
+			})
+		}
+	}
+
+	resp, err := ocr.ChatOllamaTools(ctx, messages, ollamaTools, p.cfg.Endpoint, p.model())
+	if err != nil {
+		return nil, err
+	}
+
+	reply := &ToolReply{Text: resp.Message.Content, Model: p.model()}
+	for i, tc := range resp.Message.ToolCalls {
+		args := string(tc.Function.Arguments)
+		if args == "" || args == "null" {
+			args = "{}"
+		}
+		reply.Calls = append(reply.Calls, ToolCall{
+			ID:        fmt.Sprintf("call_%d_%d", len(rounds), i),
+			Name:      tc.Function.Name,
+			Arguments: args,
+		})
+	}
+	return reply, nil
+}
+
+// ollamaMessages converts the thread history and new question into
+// /api/chat messages
+func ollamaMessages(req Request) []ocr.OllamaChatMessage {
+	messages := make([]ocr.OllamaChatMessage, 0, len(req.History)*2+1)
+	for _, turn := range req.History {
+		user := ocr.OllamaChatMessage{Role: "user", Content: turn.Question}
+		if turn.ImageBase64 != "" {
+			user.Images = []string{turn.ImageBase64}
+		}
+		messages = append(messages, user, ocr.OllamaChatMessage{Role: "assistant", Content: turn.Answer})
+	}
+
+	question := ocr.OllamaChatMessage{Role: "user", Content: req.Query}
+	if req.ImageBase64 != "" {
+		question.Images = []string{req.ImageBase64}
+	}
+	return append(messages, question)
+}
@@ -0,0 +1,313 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Kelen/Korner/internal/tokens"
+)
+
+// DefaultOpenAIEndpoint is the public OpenAI API base URL
+const DefaultOpenAIEndpoint = "https://api.openai.com/v1"
+
+// visionModelHints are substrings of model IDs that accept image input.
+// They cover OpenAI models and the common local VLMs served by LM Studio
+// and vLLM.
+var visionModelHints = []string{
+	"gpt-4o", "gpt-4.1", "gpt-4-turbo", "gpt-4-vision", "gpt-5", "o1", "o3", "o4",
+	"vision", "-vl", "vl-", "llava", "pixtral", "gemma-3", "gemma3",
+	"minicpm-v", "internvl", "idefics", "molmo", "moondream", "llama-3.2-11b", "llama-3.2-90b",
+}
+
+func init() {
+	Register("openai", func(cfg Config) Provider {
+		return &openAIProvider{cfg: cfg}
+	})
+}
+
+// openAIProvider talks to any OpenAI-compatible /chat/completions server:
+// OpenAI itself, LM Studio, vLLM and similar
+type openAIProvider struct {
+	cfg Config
+}
+
+func (p *openAIProvider) Name() string { return "openai" }
+
+// Capabilities reports vision when the configured model accepts images or
+// the user forced image input in settings
+func (p *openAIProvider) Capabilities() Capabilities {
+	return Capabilities{
+		Vision:    p.cfg.Vision || SupportsVision(p.cfg.Model),
+		Streaming: true,
+		Tools:     true,
+	}
+}
+
+// Models lists the model Query sends. Without a configured model it is the
+// one the server serves, or nothing when the server cannot be asked.
+func (p *openAIProvider) Models() []string {
+	if p.cfg.Model != "" {
+		return []string{p.cfg.Model}
+	}
+	endpoint := p.cfg.Endpoint
+	if endpoint == "" {
+		endpoint = DefaultOpenAIEndpoint
+	}
+	_, baseURL := chatCompletionsURL(endpoint)
+	if model, err := getModelName(baseURL, p.cfg.APIKey); err == nil && model != "" {
+		return []string{model}
+	}
+	return nil
+}
+
+// ListModels asks the server which models it serves
+func (p *openAIProvider) ListModels(ctx context.Context) ([]string, error) {
+	endpoint := p.cfg.Endpoint
+	if endpoint == "" {
+		endpoint = DefaultOpenAIEndpoint
+	}
+	_, baseURL := chatCompletionsURL(endpoint)
+	return listOpenAIModels(ctx, baseURL, p.cfg.APIKey)
+}
+
+func (p *openAIProvider) Query(ctx context.Context, req Request) (*Response, error) {
+	cfg, err := p.resolve()
+	if err != nil {
+		return nil, err
+	}
+	return chatCompletion(ctx, "OpenAI", cfg, req)
+}
+
+// QueryWithTools runs one step of the tool loop
+func (p *openAIProvider) QueryWithTools(ctx context.Context, req Request, tools []Tool, rounds []ToolRound, allowCalls bool) (*ToolReply, error) {
+	cfg, err := p.resolve()
+	if err != nil {
+		return nil, err
+	}
+	return chatCompletionTools(ctx, "OpenAI", cfg, req, tools, rounds, allowCalls)
+}
+
+// resolve fills in the chat completions URL and the model to use
+func (p *openAIProvider) resolve() (Config, error) {
+	endpoint := p.cfg.Endpoint
+	if endpoint == "" {
+		endpoint = DefaultOpenAIEndpoint
+	}
+	endpoint, baseURL := chatCompletionsURL(endpoint)
+	log.Printf("[OpenAI] Using endpoint: %s", endpoint)
+
+	// Local servers usually serve a single model, ask them when unset
+	model := p.cfg.Model
+	if model == "" {
+		fetched, err := getModelName(baseURL, p.cfg.APIKey)
+		if err != nil {
+			return Config{}, fmt.Errorf("no model configured and the server did not list any: %w", err)
+		}
+		model = fetched
+	}
+
+	return Config{
+		Endpoint:  endpoint,
+		APIKey:    p.cfg.APIKey,
+		Model:     model,
+		MaxTokens: p.cfg.MaxTokens,
+	}, nil
+}
+
+// SupportsVision guesses from the model ID whether it accepts images
+func SupportsVision(model string) bool {
+	model = strings.ToLower(model)
+	if model == "" {
+		return false
+	}
+	for _, hint := range visionModelHints {
+		if strings.Contains(model, hint) {
+			return true
+		}
+	}
+	return false
+}
+
+// chatCompletionsURL normalizes an OpenAI-style endpoint into the
+// /chat/completions URL and the /v1 base URL used for /models
+func chatCompletionsURL(endpoint string) (string, string) {
+	if !strings.HasSuffix(endpoint, "/chat/completions") {
+		endpoint = strings.TrimSuffix(endpoint, "/") + "/chat/completions"
+	}
+
+	// Extract base URL for model fetching
+	baseURL := strings.TrimSuffix(endpoint, "/chat/completions")
+	if !strings.HasSuffix(baseURL, "/v1") {
+		baseURL = strings.TrimSuffix(baseURL, "/") + "/v1"
+	}
+	return endpoint, baseURL
+}
+
+// chatCompletion sends a chat completion to cfg.Endpoint, which must be the
+// full /chat/completions URL, with cfg.Model already resolved. tag prefixes
+// the log lines.
+func chatCompletion(ctx context.Context, tag string, cfg Config, r Request) (*Response, error) {
+	query, screenshotBase64, language := r.Query, r.ImageBase64, r.Language
+	modelName := cfg.Model
+
+	// Build system prompt based on language
+	// Use plain text format only (no markdown)
+	systemPrompt := defaultSystemPrompt(language, query)
+
+	messages := []OpenAIMessage{
+		{
+			Role: "system",
+			Content: []OpenAIContent{
+				{
+					Type: "text",
+					Text: systemPrompt,
+				},
+			},
+		},
+	}
+
+	// Replay earlier turns of the thread before the new question
+	messages = append(messages, openAIHistory(r.History)...)
+	if len(r.History) > 0 {
+		log.Printf("[%s] Replaying %d earlier turns", tag, len(r.History))
+	}
+
+	messages = append(messages, openAIUserMessage(query, screenshotBase64))
+	if screenshotBase64 != "" {
+		log.Printf("[%s] Added image to request (base64 length: %d)", tag, len(screenshotBase64))
+	} else {
+		log.Printf("[%s] No image provided", tag)
+	}
+
+	maxTokens := cfg.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = defaultMaxTokens
+	}
+	// Long prompts leave less room for the answer in the context window
+	promptTokens := EstimateRequest(r)
+	maxTokens = tokens.OutputBudget(modelName, promptTokens, maxTokens)
+	log.Printf("[%s] Estimated prompt tokens: %d, max_tokens: %d", tag, promptTokens, maxTokens)
+
+	// Build request payload
+	// Note: Some vLLM servers may not support all OpenAI parameters
+	// Start with basic parameters and add more if needed
+	reqPayload := OpenAIRequest{
+		Model:       modelName,
+		Messages:    messages,
+		MaxTokens:   maxTokens,
+		Temperature: 0.7,
+		Stream:      r.OnChunk != nil,
+	}
+	if reqPayload.Stream {
+		// Servers that support it send the usage in the last event
+		reqPayload.StreamOptions = &StreamOptions{IncludeUsage: true}
+	}
+	if r.Schema != nil {
+		// vLLM, LM Studio and OpenAI constrain the output to the schema
+		reqPayload.ResponseFormat = &OpenAIResponseFormat{
+			Type:       "json_schema",
+			JSONSchema: &OpenAIJSONSchema{Name: r.Schema.Name, Schema: r.Schema.Schema},
+		}
+	}
+
+	// Optionally add advanced parameters (may not be supported by all vLLM servers)
+	// Uncomment if your vLLM server supports these:
+	// reqPayload.TopP = 0.9
+	// reqPayload.FrequencyPenalty = 0.3
+	// reqPayload.PresencePenalty = 0.3
+
+	log.Printf("[%s] Using model: %s", tag, modelName)
+
+	body, err := json.Marshal(reqPayload)
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+
+	httpClient := &http.Client{Timeout: 120 * time.Second}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+
+	// Local servers such as LM Studio accept requests without a key
+	if cfg.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+cfg.APIKey)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	log.Printf("[%s] Sending request...", tag)
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("http request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		log.Printf("[%s] ERROR response: %s", tag, string(bodyBytes))
+		return nil, apiError(resp.StatusCode, bodyBytes)
+	}
+
+	var responseText, reasoning string
+	var usage Usage
+	if r.OnChunk != nil {
+		streamed, streamedReasoning, streamedUsage, err := readOpenAIStream(resp.Body, r.OnChunk)
+		if err != nil {
+			return nil, err
+		}
+		responseText = strings.TrimSpace(streamed)
+		reasoning = streamedReasoning
+		usage = streamedUsage
+	} else {
+		var result OpenAIResponse
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			return nil, fmt.Errorf("decode response: %w", err)
+		}
+
+		if len(result.Choices) == 0 {
+			return nil, errors.New("no response from API")
+		}
+
+		responseText = strings.TrimSpace(result.Choices[0].Message.Content)
+		reasoning = result.Choices[0].Message.reasoningText()
+		usage = result.Usage.usage()
+	}
+	log.Printf("[%s] Raw response length: %d", tag, len(responseText))
+
+	if responseText == "" {
+		log.Printf("[%s] WARNING: Empty response from API", tag)
+		return nil, errors.New("empty response from API")
+	}
+
+	// Log first 200 chars to see the format
+	preview := responseText
+	if len(preview) > 200 {
+		preview = preview[:200] + "..."
+	}
+	log.Printf("[%s] Raw response preview: %q", tag, preview)
+
+	// Split internal reasoning from the response
+	originalText := responseText
+	responseText = cleanResponseText(responseText)
+	reasoning = joinNonEmpty([]string{reasoning, ParseResponse(originalText).Reasoning}, "\n\n")
+
+	if responseText != originalText {
+		log.Printf("[%s] Cleaned response (removed %d chars)", tag, len(originalText)-len(responseText))
+		cleanedPreview := responseText
+		if len(cleanedPreview) > 200 {
+			cleanedPreview = cleanedPreview[:200] + "..."
+		}
+		log.Printf("[%s] Cleaned response preview: %q", tag, cleanedPreview)
+	}
+
+	log.Printf("[%s] Success! Final response length: %d", tag, len(responseText))
+	return &Response{Text: responseText, Model: modelName, Reasoning: reasoning, Usage: usage}, nil
+}
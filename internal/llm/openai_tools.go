@@ -0,0 +1,140 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+// openAITools converts tools to the OpenAI "tools" request field
+func openAITools(tools []Tool) []OpenAITool {
+	out := make([]OpenAITool, 0, len(tools))
+	for _, tool := range tools {
+		out = append(out, OpenAITool{
+			Type: "function",
+			Function: OpenAIToolFunction{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  tool.Parameters,
+			},
+		})
+	}
+	return out
+}
+
+// openAIToolMessages replays earlier tool rounds as assistant tool_calls
+// followed by one tool message per result
+func openAIToolMessages(rounds []ToolRound) []OpenAIMessage {
+	var messages []OpenAIMessage
+	for _, round := range rounds {
+		assistant := OpenAIMessage{Role: "assistant"}
+		if round.Text != "" {
+			assistant.Content = []OpenAIContent{{Type: "text", Text: round.Text}}
+		}
+		for _, call := range round.Calls {
+			tc := OpenAIToolCall{ID: call.ID, Type: "function"}
+			tc.Function.Name = call.Name
+			tc.Function.Arguments = call.Arguments
+			assistant.ToolCalls = append(assistant.ToolCalls, tc)
+		}
+		messages = append(messages, assistant)
+
+		for _, call := range round.Calls {
+			messages = append(messages, OpenAIMessage{
+				Role:       "tool",
+				ToolCallID: call.ID,
+				Content:    []OpenAIContent{{Type: "text", Text: toolOutput(call)}},
+			})
+		}
+	}
+	return messages
+}
+
+// chatCompletionTools sends one non-streamed step of the tool loop to
+// cfg.Endpoint, which must be the full /chat/completions URL
+func chatCompletionTools(ctx context.Context, tag string, cfg Config, r Request, tools []Tool, rounds []ToolRound, allowCalls bool) (*ToolReply, error) {
+	messages := []OpenAIMessage{
+		{
+			Role:    "system",
+			Content: []OpenAIContent{{Type: "text", Text: defaultSystemPrompt(r.Language, r.Query)}},
+		},
+	}
+	messages = append(messages, openAIHistory(r.History)...)
+	messages = append(messages, openAIUserMessage(r.Query, r.ImageBase64))
+	messages = append(messages, openAIToolMessages(rounds)...)
+
+	maxTokens := cfg.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = defaultMaxTokens
+	}
+
+	reqPayload := OpenAIRequest{
+		Model:       cfg.Model,
+		Messages:    messages,
+		MaxTokens:   maxTokens,
+		Temperature: 0.7,
+		Tools:       openAITools(tools),
+	}
+	if !allowCalls && len(reqPayload.Tools) > 0 {
+		// Out of iterations, the model has to answer with what it has
+		reqPayload.ToolChoice = "none"
+	}
+
+	body, err := json.Marshal(reqPayload)
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+
+	httpClient := &http.Client{Timeout: 120 * time.Second}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	if cfg.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+cfg.APIKey)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	log.Printf("[%s] Sending tool step (model: %s, rounds: %d, calls allowed: %v)", tag, cfg.Model, len(rounds), allowCalls)
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("http request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		log.Printf("[%s] ERROR response: %s", tag, string(bodyBytes))
+		return nil, apiError(resp.StatusCode, bodyBytes)
+	}
+
+	var result OpenAIResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	if len(result.Choices) == 0 {
+		return nil, errors.New("no response from API")
+	}
+
+	message := result.Choices[0].Message
+	reply := &ToolReply{Text: message.Content, Model: cfg.Model, Reasoning: message.reasoningText()}
+	for i, tc := range message.ToolCalls {
+		id := tc.ID
+		if id == "" {
+			// Some local servers leave the ID out, the reply still has to reference one
+			id = fmt.Sprintf("call_%d_%d", len(rounds), i)
+		}
+		reply.Calls = append(reply.Calls, ToolCall{
+			ID:        id,
+			Name:      tc.Function.Name,
+			Arguments: tc.Function.Arguments,
+		})
+	}
+	return reply, nil
+}
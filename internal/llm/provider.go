@@ -0,0 +1,115 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Capabilities describes what a provider backend can handle
+type Capabilities struct {
+	Vision    bool `json:"vision"`    // Accepts images alongside the prompt
+	Streaming bool `json:"streaming"` // Can stream partial tokens
+	Tools     bool `json:"tools"`     // Supports tool/function calling
+}
+
+// Config holds the connection settings used to build a provider
+type Config struct {
+	Endpoint  string
+	APIKey    string
+	Model     string // Optional, empty means the provider default
+	MaxTokens int    // Optional, zero means the provider default
+	Vision    bool   // Forces image input for models not recognised as vision models
+}
+
+// StreamFunc receives each partial chunk of a streamed answer
+type StreamFunc func(chunk string)
+
+// Request is a single query sent to a provider
+type Request struct {
+	Query       string
+	ImageBase64 string // Only sent when the provider supports vision
+	Language    string
+	OnChunk     StreamFunc  // Optional, streams the answer when the provider supports it
+	History     []Turn      // Earlier turns of the thread, oldest first
+	Schema      *JSONSchema // Optional, asks for a JSON answer, see QueryJSON
+}
+
+// Response is the answer returned by a provider
+type Response struct {
+	Text      string
+	Model     string     // The model that actually produced the answer
+	Reasoning string     // The model's thinking, kept apart from Text
+	ToolCalls []ToolCall // Tools the model called while answering, in order
+	Usage     Usage      // Zero when the server did not report it
+	Cached    bool       `json:"-"` // Served by WithCache without calling the model
+}
+
+// Usage is the token count a server reports for one answer
+type Usage struct {
+	PromptTokens     int `json:"promptTokens"`
+	CompletionTokens int `json:"completionTokens"`
+}
+
+// Provider is implemented by every LLM backend
+type Provider interface {
+	Name() string
+	Query(ctx context.Context, req Request) (*Response, error)
+	Capabilities() Capabilities
+	Models() []string
+}
+
+// configuredModel returns the model p sends its requests to, which
+// providers list first in Models, or "" when it is not known
+func configuredModel(p Provider) string {
+	if models := p.Models(); len(models) > 0 {
+		return models[0]
+	}
+	return ""
+}
+
+// Factory creates a provider from its configuration
+type Factory func(cfg Config) Provider
+
+var (
+	registry      = make(map[string]Factory)
+	registryMutex sync.RWMutex
+)
+
+// Register makes a provider available under the given name.
+// Backends call it from their init function.
+func Register(name string, factory Factory) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+
+	if _, exists := registry[name]; exists {
+		panic(fmt.Sprintf("llm: provider %q registered twice", name))
+	}
+	registry[name] = factory
+}
+
+// NewProvider builds the provider registered under name
+func NewProvider(name string, cfg Config) (Provider, error) {
+	registryMutex.RLock()
+	factory, ok := registry[name]
+	registryMutex.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown provider: %s", name)
+	}
+	return factory(cfg), nil
+}
+
+// Providers returns the names of all registered providers in sorted order
+func Providers() []string {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,217 @@
+package llm
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ParsedResponse is a model answer split into its reasoning and the text
+// meant for the user
+type ParsedResponse struct {
+	Reasoning string
+	Final     string
+}
+
+// harmonyMessage matches one message of the gpt-oss harmony format:
+// <|channel|>analysis<|message|>...<|end|>
+var harmonyMessage = regexp.MustCompile(`(?s)<\|channel\|>\s*(\w+)[^<]*<\|message\|>(.*?)(?:<\|end\|>|<\|return\|>|<\|call\|>|<\|start\|>|$)`)
+
+// thinkTags are the reasoning blocks emitted by DeepSeek, Qwen and similar models
+var thinkTags = []string{"think", "thinking", "reasoning"}
+
+// reasoningLeads start the planning sentences gpt-oss leaks when the
+// harmony markers are stripped by the server. They are only looked for
+// after a glued "analysis" channel name, normal answers start this way too.
+var reasoningLeads = []string{
+	"user asks", "user says", "user wants", "user is asking", "user requests",
+	"the user asks", "the user says", "the user wants", "the user is asking",
+	"need to", "we need to", "i need to",
+}
+
+// finalMarkers separate flattened harmony reasoning from the answer
+var finalMarkers = []string{"assistantfinal", "assistant_final", "final_response"}
+
+// ParseResponse splits raw model output into reasoning and final answer. It
+// understands the harmony channel format of gpt-oss, including the flattened
+// "analysis…assistantfinal…" form some servers return, and <think> style
+// tags. Text without reasoning is returned unchanged as Final.
+func ParseResponse(text string) ParsedResponse {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return ParsedResponse{}
+	}
+
+	if strings.Contains(text, "<|channel|>") {
+		return parseHarmony(text)
+	}
+
+	var reasoning []string
+	final := text
+
+	// Flattened harmony: the special tokens are dropped, leaving the channel
+	// names glued to the text
+	lower := strings.ToLower(final)
+	split := false
+	for _, marker := range finalMarkers {
+		if idx := strings.LastIndex(lower, marker); idx != -1 {
+			reasoning = append(reasoning, trimChannelName(final[:idx]))
+			final = final[idx+len(marker):]
+			split = true
+			break
+		}
+	}
+	if !split && gluedAnalysis(final) {
+		// Reasoning without a final marker: the glued "assistant" role name,
+		// planning sentences or the text before a numbered list end it
+		body := final[len("analysis"):]
+		if idx := strings.LastIndex(body, "assistant"); idx > 0 {
+			reasoning = append(reasoning, body[:idx])
+			final = body[idx+len("assistant"):]
+		} else if lead, rest := splitReasoningLead(body); lead != "" {
+			reasoning = append(reasoning, lead)
+			final = rest
+		} else if idx := strings.Index(body, "1."); idx > 0 && idx < 500 {
+			reasoning = append(reasoning, body[:idx])
+			final = body[idx:]
+		}
+	}
+
+	for _, tag := range thinkTags {
+		var blocks []string
+		blocks, final = extractTag(final, tag)
+		reasoning = append(reasoning, blocks...)
+	}
+
+	return ParsedResponse{
+		Reasoning: joinNonEmpty(reasoning, "\n\n"),
+		Final:     strings.TrimSpace(final),
+	}
+}
+
+// parseHarmony collects the analysis and commentary channels as reasoning
+// and the final channel as the answer
+func parseHarmony(text string) ParsedResponse {
+	var reasoning, final []string
+	for _, m := range harmonyMessage.FindAllStringSubmatch(text, -1) {
+		content := strings.TrimSpace(m[2])
+		if m[1] == "final" {
+			final = append(final, content)
+		} else {
+			reasoning = append(reasoning, content)
+		}
+	}
+	return ParsedResponse{
+		Reasoning: joinNonEmpty(reasoning, "\n\n"),
+		Final:     joinNonEmpty(final, "\n\n"),
+	}
+}
+
+// extractTag removes every <tag>…</tag> block from text and returns their
+// contents. A closing tag without an opening one means the opening tag was
+// part of the prompt template, so everything before it is reasoning. An
+// unclosed block runs to the end of the text.
+func extractTag(text string, tag string) ([]string, string) {
+	open, closing := "<"+tag+">", "</"+tag+">"
+	var blocks []string
+
+	lower := strings.ToLower(text)
+	if end := strings.Index(lower, closing); end != -1 && !strings.Contains(lower[:end], open) {
+		blocks = append(blocks, strings.TrimSpace(text[:end]))
+		text = text[end+len(closing):]
+	}
+
+	for {
+		lower = strings.ToLower(text)
+		start := strings.Index(lower, open)
+		if start == -1 {
+			break
+		}
+		end := strings.Index(lower[start:], closing)
+		if end == -1 {
+			blocks = append(blocks, strings.TrimSpace(text[start+len(open):]))
+			text = text[:start]
+			break
+		}
+		blocks = append(blocks, strings.TrimSpace(text[start+len(open):start+end]))
+		text = text[:start] + text[start+end+len(closing):]
+	}
+	return blocks, text
+}
+
+// splitReasoningLead moves leading planning sentences such as "User asks
+// a question. Need to respond clearly." into the reasoning. The text is only
+// split when an answer remains after them.
+func splitReasoningLead(text string) (string, string) {
+	rest := text
+	for startsWithLead(rest) {
+		end := sentenceEnd(rest)
+		if end == -1 {
+			return "", text
+		}
+		rest = strings.TrimLeftFunc(rest[end:], unicode.IsSpace)
+	}
+	if rest == text || rest == "" {
+		return "", text
+	}
+	return strings.TrimSpace(text[:len(text)-len(rest)]), rest
+}
+
+func startsWithLead(s string) bool {
+	lower := strings.ToLower(s)
+	for _, lead := range reasoningLeads {
+		if strings.HasPrefix(lower, lead) {
+			return true
+		}
+	}
+	return false
+}
+
+// sentenceEnd returns the index just past the first sentence-ending period
+func sentenceEnd(s string) int {
+	for i, r := range s {
+		if r == '.' || r == '?' || r == '!' {
+			next := i + 1
+			if next == len(s) {
+				return next
+			}
+			// Skip decimals and abbreviations like "e.g" inside a sentence
+			if c := s[next]; c == ' ' || c == '\n' || c >= 0x80 {
+				return next
+			}
+		}
+	}
+	return -1
+}
+
+// gluedAnalysis reports whether text starts with the "analysis" channel name
+// glued to the reasoning, as in "analysisUser asks…", which is how servers
+// that drop the harmony tokens return gpt-oss output. Answers that start
+// with the word, like "Analysis: …" or "Analysis of…", do not match.
+func gluedAnalysis(text string) bool {
+	if !strings.HasPrefix(text, "analysis") || len(text) == len("analysis") {
+		return false
+	}
+	r, _ := utf8.DecodeRuneInString(text[len("analysis"):])
+	return unicode.IsUpper(r)
+}
+
+// trimChannelName drops a leading "analysis" channel name
+func trimChannelName(s string) string {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(strings.ToLower(s), "analysis") {
+		s = s[len("analysis"):]
+	}
+	return strings.TrimSpace(s)
+}
+
+func joinNonEmpty(parts []string, sep string) string {
+	kept := parts[:0:0]
+	for _, p := range parts {
+		if p = strings.TrimSpace(p); p != "" {
+			kept = append(kept, p)
+		}
+	}
+	return strings.Join(kept, sep)
+}
@@ -0,0 +1,97 @@
+package llm
+
+import "testing"
+
+func TestParseResponse(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		reasoning string
+		final     string
+	}{
+		{
+			name:      "Harmony channels",
+			input:     "<|channel|>analysis<|message|>User says hi. Reply politely.<|end|><|start|>assistant<|channel|>final<|message|>你好！<|return|>",
+			reasoning: "User says hi. Reply politely.",
+			final:     "你好！",
+		},
+		{
+			name:      "Harmony commentary counts as reasoning",
+			input:     "<|channel|>analysis<|message|>Plan.<|end|><|channel|>commentary<|message|>Checked.<|end|><|channel|>final<|message|>Done.",
+			reasoning: "Plan.\n\nChecked.",
+			final:     "Done.",
+		},
+		{
+			name:      "Flattened harmony",
+			input:     "analysisUser says hi.assistantfinal你好！",
+			reasoning: "User says hi.",
+			final:     "你好！",
+		},
+		{
+			name:      "Think tags",
+			input:     "<think>\nThe user greets me.\n</think>\n\nHello!",
+			reasoning: "The user greets me.",
+			final:     "Hello!",
+		},
+		{
+			name:      "Closing think tag only",
+			input:     "The user greets me.</think>Hello!",
+			reasoning: "The user greets me.",
+			final:     "Hello!",
+		},
+		{
+			name:      "Unclosed think tag",
+			input:     "Answer first.<think>still thinking",
+			reasoning: "still thinking",
+			final:     "Answer first.",
+		},
+		{
+			name:      "Flattened analysis without final marker",
+			input:     "analysisUser asks a question. Need to respond clearly. Hello!",
+			reasoning: "User asks a question. Need to respond clearly.",
+			final:     "Hello!",
+		},
+		{
+			name:      "Answer starting with Analysis",
+			input:     "Analysis of the report: the assistant role grew 5%. Ask your assistant for details.",
+			reasoning: "",
+			final:     "Analysis of the report: the assistant role grew 5%. Ask your assistant for details.",
+		},
+		{
+			name:      "Answer starting with Need to",
+			input:     "Need to renew a passport? Book online. The office opens at 9.",
+			reasoning: "",
+			final:     "Need to renew a passport? Book online. The office opens at 9.",
+		},
+		{
+			name:      "Answer starting with I need to",
+			input:     "I need to point out that the chart has no units. Otherwise it looks fine.",
+			reasoning: "",
+			final:     "I need to point out that the chart has no units. Otherwise it looks fine.",
+		},
+		{
+			name:      "Answer starting with User asks",
+			input:     "User asks are logged in the audit table. Query it by date.",
+			reasoning: "",
+			final:     "User asks are logged in the audit table. Query it by date.",
+		},
+		{
+			name:      "Plain answer",
+			input:     "Version 1.2 is out. Need to know more?",
+			reasoning: "",
+			final:     "Version 1.2 is out. Need to know more?",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseResponse(tt.input)
+			if got.Reasoning != tt.reasoning {
+				t.Errorf("Reasoning = %q, want %q", got.Reasoning, tt.reasoning)
+			}
+			if got.Final != tt.final {
+				t.Errorf("Final = %q, want %q", got.Final, tt.final)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package llm
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+)
+
+// readOpenAIStream reads a server-sent event stream from /chat/completions,
+// passes every content delta to onChunk and returns the full text together
+// with any reasoning the server sent in its own field and the usage of the
+// last event
+func readOpenAIStream(body io.Reader, onChunk StreamFunc) (string, string, Usage, error) {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var full, reasoning strings.Builder
+	var usage Usage
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			continue // Blank separators, comments and event names
+		}
+
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			break
+		}
+
+		var event OpenAIStreamResponse
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			log.Printf("[Stream] Skipping malformed event: %v", err)
+			continue
+		}
+		if event.Usage != nil {
+			usage = event.Usage.usage()
+		}
+		if len(event.Choices) == 0 {
+			continue
+		}
+
+		delta := event.Choices[0].Delta
+		reasoning.WriteString(delta.reasoningText())
+		chunk := delta.Content
+		if chunk == "" {
+			continue
+		}
+		full.WriteString(chunk)
+		onChunk(chunk)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return full.String(), reasoning.String(), usage, fmt.Errorf("read stream: %w", err)
+	}
+	return full.String(), reasoning.String(), usage, nil
+}
@@ -0,0 +1,65 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestChatCompletionStreamUsage(t *testing.T) {
+	var got OpenAIRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(`data: {"choices":[{"delta":{"content":"Hello"}}]}
+
+data: {"choices":[],"usage":{"prompt_tokens":12,"completion_tokens":3}}
+
+data: [DONE]
+
+`))
+	}))
+	defer server.Close()
+
+	var streamed string
+	resp, err := chatCompletion(context.Background(), "Test", Config{Endpoint: server.URL, Model: "gpt-4o"}, Request{
+		Query:   "hi",
+		OnChunk: func(chunk string) { streamed += chunk },
+	})
+	if err != nil {
+		t.Fatalf("chatCompletion: %v", err)
+	}
+	if got.StreamOptions == nil || !got.StreamOptions.IncludeUsage {
+		t.Errorf("stream_options = %+v, want include_usage", got.StreamOptions)
+	}
+	if streamed != "Hello" || resp.Usage != (Usage{PromptTokens: 12, CompletionTokens: 3}) {
+		t.Errorf("streamed %q with usage %+v", streamed, resp.Usage)
+	}
+}
+
+func TestReadAnthropicStreamUsage(t *testing.T) {
+	stream := `event: message_start
+data: {"type":"message_start","message":{"usage":{"input_tokens":25,"output_tokens":1}}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","delta":{"type":"text_delta","text":"Hi"}}
+
+event: message_delta
+data: {"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":15}}
+
+event: message_stop
+data: {"type":"message_stop"}
+`
+	text, usage, err := readAnthropicStream(strings.NewReader(stream), func(string) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != "Hi" || usage != (Usage{PromptTokens: 25, CompletionTokens: 15}) {
+		t.Errorf("got %q with usage %+v", text, usage)
+	}
+}
@@ -0,0 +1,256 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/Kelen/Korner/internal/prompts"
+)
+
+// DefaultJSONAttempts is how often QueryJSON asks before giving up on an
+// answer that does not match the schema
+const DefaultJSONAttempts = 3
+
+// JSONSchema describes the JSON answer a request asks for
+type JSONSchema struct {
+	Name   string                 // Identifier sent to OpenAI-compatible servers
+	Schema map[string]interface{} // JSON Schema, same form as Tool.Parameters
+}
+
+// QueryJSON asks p for an answer matching schema and decodes it into out.
+// OpenAI-compatible servers and Ollama constrain the output to the schema,
+// other providers rely on the instructions added to the question. Answers
+// that do not validate are sent back with the problem until attempts run
+// out.
+func QueryJSON(ctx context.Context, p Provider, req Request, schema JSONSchema, out interface{}) (*Response, error) {
+	schema.Schema = normalizeSchema(schema.Schema)
+	schemaText, err := json.MarshalIndent(schema.Schema, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshal schema: %w", err)
+	}
+
+	req.Schema = &schema
+	req.OnChunk = nil
+	data := prompts.NewData(req.Language)
+	data.Query = req.Query
+	data.Schema = string(schemaText)
+
+	var problem error
+	for attempt := 1; attempt <= DefaultJSONAttempts; attempt++ {
+		if problem != nil {
+			data.Problem = problem.Error()
+		}
+		req.Query = prompts.Render(prompts.JSON, data)
+
+		resp, err := p.Query(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		if problem = decodeJSON(resp.Text, schema.Schema, out); problem == nil {
+			return resp, nil
+		}
+		log.Printf("[JSON] %s attempt %d/%d invalid: %v", p.Name(), attempt, DefaultJSONAttempts, problem)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+	}
+	return nil, fmt.Errorf("no valid %s JSON after %d attempts: %w", schema.Name, DefaultJSONAttempts, problem)
+}
+
+// decodeJSON finds the JSON in text, validates it and decodes it into out
+func decodeJSON(text string, schema map[string]interface{}, out interface{}) error {
+	raw := extractJSON(text)
+	var value interface{}
+	if err := json.Unmarshal([]byte(raw), &value); err != nil {
+		return fmt.Errorf("not valid JSON: %w", err)
+	}
+	if err := ValidateJSON(schema, value); err != nil {
+		return err
+	}
+	if err := json.Unmarshal([]byte(raw), out); err != nil {
+		return fmt.Errorf("decode: %w", err)
+	}
+	return nil
+}
+
+// extractJSON strips code fences and text around the outermost JSON value
+func extractJSON(text string) string {
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "```") {
+		text = strings.TrimPrefix(text, "```json")
+		text = strings.TrimPrefix(text, "```")
+		text = strings.TrimSuffix(strings.TrimSpace(text), "```")
+		text = strings.TrimSpace(text)
+	}
+
+	start := strings.IndexAny(text, "{[")
+	if start == -1 {
+		return text
+	}
+	closing := "}"
+	if text[start] == '[' {
+		closing = "]"
+	}
+	end := strings.LastIndex(text, closing)
+	if end < start {
+		return text[start:]
+	}
+	return text[start : end+1]
+}
+
+// normalizeSchema round-trips schema through JSON so enum values and
+// nested maps have the types ValidateJSON expects
+func normalizeSchema(schema map[string]interface{}) map[string]interface{} {
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return schema
+	}
+	var normalized map[string]interface{}
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return schema
+	}
+	return normalized
+}
+
+// ValidateJSON checks a decoded JSON value against schema. It supports the
+// keywords the app uses: type, properties, required, additionalProperties,
+// items and enum.
+func ValidateJSON(schema map[string]interface{}, value interface{}) error {
+	return validateValue(normalizeSchema(schema), value, "$")
+}
+
+func validateValue(schema map[string]interface{}, value interface{}, path string) error {
+	if types := schemaTypes(schema["type"]); len(types) > 0 {
+		matched := false
+		for _, t := range types {
+			if hasType(t, value) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fmt.Errorf("%s: expected %s, got %s", path, strings.Join(types, " or "), jsonType(value))
+		}
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, allowed := range enum {
+			if reflect.DeepEqual(allowed, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s: %v is not one of %v", path, value, enum)
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		properties, _ := schema["properties"].(map[string]interface{})
+		if required, ok := schema["required"].([]interface{}); ok {
+			for _, name := range required {
+				key, _ := name.(string)
+				if _, present := v[key]; !present {
+					return fmt.Errorf("%s: missing required property %q", path, key)
+				}
+			}
+		}
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			propSchema, known := properties[key].(map[string]interface{})
+			if !known {
+				if additional, ok := schema["additionalProperties"].(bool); ok && !additional {
+					return fmt.Errorf("%s: unexpected property %q", path, key)
+				}
+				continue
+			}
+			if err := validateValue(propSchema, v[key], path+"."+key); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		items, ok := schema["items"].(map[string]interface{})
+		if !ok {
+			break
+		}
+		for i, item := range v {
+			if err := validateValue(items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// schemaTypes reads "type", which is a string or a list of strings
+func schemaTypes(t interface{}) []string {
+	switch t := t.(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		types := make([]string, 0, len(t))
+		for _, name := range t {
+			if s, ok := name.(string); ok {
+				types = append(types, s)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+func hasType(t string, value interface{}) bool {
+	switch t {
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		n, ok := value.(float64)
+		return ok && n == math.Trunc(n)
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	}
+	return true
+}
+
+func jsonType(value interface{}) string {
+	switch value.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case nil:
+		return "null"
+	}
+	return fmt.Sprintf("%T", value)
+}
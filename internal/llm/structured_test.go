@@ -0,0 +1,118 @@
+package llm
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+var itemSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"item":     map[string]interface{}{"type": "string"},
+		"priority": map[string]interface{}{"type": "string", "enum": []string{"high", "medium", "low"}},
+		"count":    map[string]interface{}{"type": "integer"},
+		"tags":     map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+	},
+	"required":             []string{"item", "priority"},
+	"additionalProperties": false,
+}
+
+func TestValidateJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr string
+	}{
+		{name: "Valid", input: `{"item":"write notes","priority":"high","count":2,"tags":["a"]}`},
+		{name: "Missing required", input: `{"item":"x"}`, wantErr: `missing required property "priority"`},
+		{name: "Wrong type", input: `{"item":3,"priority":"low"}`, wantErr: "$.item: expected string"},
+		{name: "Not in enum", input: `{"item":"x","priority":"urgent"}`, wantErr: "is not one of"},
+		{name: "Not an integer", input: `{"item":"x","priority":"low","count":1.5}`, wantErr: "expected integer"},
+		{name: "Array item", input: `{"item":"x","priority":"low","tags":[1]}`, wantErr: "$.tags[0]"},
+		{name: "Extra property", input: `{"item":"x","priority":"low","owner":"me"}`, wantErr: `unexpected property "owner"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out map[string]interface{}
+			err := decodeJSON(tt.input, normalizeSchema(itemSchema), &out)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExtractJSON(t *testing.T) {
+	tests := map[string]string{
+		`{"a":1}`:                          `{"a":1}`,
+		"```json\n{\"a\":1}\n```":          `{"a":1}`,
+		"Here you go:\n{\"a\":1}\nThanks!": `{"a":1}`,
+		`[1,2]`:                            `[1,2]`,
+	}
+	for input, want := range tests {
+		if got := extractJSON(input); got != want {
+			t.Errorf("extractJSON(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+// scriptedProvider returns its answers in order and records the queries
+type scriptedProvider struct {
+	fakeProvider
+	answers []string
+	queries []string
+}
+
+func (s *scriptedProvider) Query(ctx context.Context, req Request) (*Response, error) {
+	s.queries = append(s.queries, req.Query)
+	answer := s.answers[0]
+	s.answers = s.answers[1:]
+	return &Response{Text: answer}, nil
+}
+
+func TestQueryJSONRetriesWithProblem(t *testing.T) {
+	p := &scriptedProvider{
+		fakeProvider: fakeProvider{name: "fake"},
+		answers:      []string{`{"item":"x"}`, `{"item":"x","priority":"low"}`},
+	}
+	var out struct {
+		Item     string `json:"item"`
+		Priority string `json:"priority"`
+	}
+	_, err := QueryJSON(context.Background(), p, Request{Query: "list it", Language: "en"}, JSONSchema{Name: "item", Schema: itemSchema}, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Item != "x" || out.Priority != "low" {
+		t.Errorf("decoded %+v", out)
+	}
+	if len(p.queries) != 2 {
+		t.Fatalf("expected 2 attempts, got %d", len(p.queries))
+	}
+	if !strings.Contains(p.queries[1], `missing required property "priority"`) {
+		t.Errorf("retry does not explain the problem: %q", p.queries[1])
+	}
+}
+
+func TestQueryJSONGivesUp(t *testing.T) {
+	p := &scriptedProvider{
+		fakeProvider: fakeProvider{name: "fake"},
+		answers:      []string{"no", "still no", "never"},
+	}
+	var out map[string]interface{}
+	_, err := QueryJSON(context.Background(), p, Request{Query: "list it"}, JSONSchema{Name: "item", Schema: itemSchema}, &out)
+	if err == nil {
+		t.Fatal("expected an error after invalid answers")
+	}
+	if len(p.queries) != DefaultJSONAttempts {
+		t.Errorf("made %d attempts, want %d", len(p.queries), DefaultJSONAttempts)
+	}
+}
@@ -0,0 +1,83 @@
+package llm
+
+import "github.com/Kelen/Korner/internal/tokens"
+
+const (
+	// DefaultHistoryBudget is the approximate number of tokens earlier turns
+	// of a thread may use before the oldest ones are dropped
+	DefaultHistoryBudget = 6000
+
+	// imageTokenCost is a rough token cost for a replayed screenshot
+	imageTokenCost = 800
+)
+
+// Turn is an earlier question and answer in a conversation thread
+type Turn struct {
+	Question    string
+	Answer      string
+	ImageBase64 string // Screenshot sent with the question, if any
+}
+
+// TrimHistory keeps the most recent turns that fit within budget tokens.
+// Turns are returned oldest first, ready to be replayed before a new query.
+func TrimHistory(turns []Turn, budget int) []Turn {
+	if budget <= 0 {
+		return nil
+	}
+
+	used := 0
+	start := len(turns)
+	for i := len(turns) - 1; i >= 0; i-- {
+		cost := tokens.Estimate(turns[i].Question) + tokens.Estimate(turns[i].Answer)
+		if turns[i].ImageBase64 != "" {
+			cost += imageTokenCost
+		}
+		if used+cost > budget {
+			break
+		}
+		used += cost
+		start = i
+	}
+	return turns[start:]
+}
+
+// openAIUserMessage builds a user message with an optional image
+func openAIUserMessage(text string, imageBase64 string) OpenAIMessage {
+	msg := OpenAIMessage{
+		Role: "user",
+		Content: []OpenAIContent{
+			{
+				Type: "text",
+				Text: text,
+			},
+		},
+	}
+	if imageBase64 != "" {
+		msg.Content = append(msg.Content, OpenAIContent{
+			Type: "image_url",
+			ImageURL: &OpenAIImageURL{
+				URL: normalizeDataURL(imageBase64),
+			},
+		})
+	}
+	return msg
+}
+
+// openAIHistory turns earlier thread turns into alternating user and
+// assistant messages
+func openAIHistory(turns []Turn) []OpenAIMessage {
+	messages := make([]OpenAIMessage, 0, len(turns)*2)
+	for _, turn := range turns {
+		messages = append(messages, openAIUserMessage(turn.Question, turn.ImageBase64))
+		messages = append(messages, OpenAIMessage{
+			Role: "assistant",
+			Content: []OpenAIContent{
+				{
+					Type: "text",
+					Text: turn.Answer,
+				},
+			},
+		})
+	}
+	return messages
+}
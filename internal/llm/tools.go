@@ -0,0 +1,169 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// DefaultMaxToolIterations bounds how many rounds of tool calls the model
+// may make before it has to answer
+const DefaultMaxToolIterations = 4
+
+// maxToolResultLength keeps a single tool result from flooding the context
+const maxToolResultLength = 6000
+
+// Tool is a function the model may call while answering
+type Tool struct {
+	Name        string
+	Description string
+	Parameters  map[string]interface{} // JSON Schema of the arguments object
+	Run         func(ctx context.Context, args json.RawMessage) (string, error)
+}
+
+// ToolCall records one call the model made and what it returned
+type ToolCall struct {
+	ID        string `json:"id,omitempty"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+	Result    string `json:"result,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// ToolRound is one assistant turn that asked for tools, with the results
+type ToolRound struct {
+	Text  string // Text the model wrote alongside the calls, often empty
+	Calls []ToolCall
+}
+
+// ToolReply is the model's answer to one step of the tool loop. Either
+// Calls is non-empty or Text holds the final answer.
+type ToolReply struct {
+	Text      string
+	Model     string
+	Reasoning string // Reasoning the server returned in its own field
+	Calls     []ToolCall
+}
+
+// ToolCaller is implemented by providers that support function calling.
+// rounds holds the earlier calls of this request with their results. When
+// allowCalls is false the model must answer without calling more tools.
+type ToolCaller interface {
+	QueryWithTools(ctx context.Context, req Request, tools []Tool, rounds []ToolRound, allowCalls bool) (*ToolReply, error)
+}
+
+// RunTools runs the agent loop: the model may call tools up to maxIterations
+// times, each result is sent back, and the final answer is returned with
+// every call that was made. Tool failures are reported to the model instead
+// of ending the loop.
+func RunTools(ctx context.Context, p ToolCaller, req Request, tools []Tool, maxIterations int) (*Response, error) {
+	if maxIterations <= 0 {
+		maxIterations = DefaultMaxToolIterations
+	}
+
+	byName := make(map[string]Tool, len(tools))
+	for _, tool := range tools {
+		byName[tool.Name] = tool
+	}
+
+	var rounds []ToolRound
+	var calls []ToolCall
+	for iteration := 0; ; iteration++ {
+		allowCalls := iteration < maxIterations
+		reply, err := p.QueryWithTools(ctx, req, tools, rounds, allowCalls)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(reply.Calls) == 0 || !allowCalls {
+			raw := strings.TrimSpace(reply.Text)
+			text := cleanResponseText(raw)
+			if text == "" {
+				return nil, fmt.Errorf("empty response from API")
+			}
+			if req.OnChunk != nil {
+				// Tool rounds are not streamed, deliver the answer in one piece
+				req.OnChunk(text)
+			}
+			reasoning := joinNonEmpty([]string{reply.Reasoning, ParseResponse(raw).Reasoning}, "\n\n")
+			return &Response{Text: text, Model: reply.Model, Reasoning: reasoning, ToolCalls: calls}, nil
+		}
+
+		round := ToolRound{Text: reply.Text}
+		for _, call := range reply.Calls {
+			call = runTool(ctx, byName, call)
+			round.Calls = append(round.Calls, call)
+			calls = append(calls, call)
+		}
+		rounds = append(rounds, round)
+
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// runTool executes one call and stores its result or error on it
+func runTool(ctx context.Context, byName map[string]Tool, call ToolCall) ToolCall {
+	log.Printf("[Tools] Calling %s(%s)", call.Name, call.Arguments)
+
+	tool, ok := byName[call.Name]
+	if !ok {
+		call.Error = fmt.Sprintf("unknown tool: %s", call.Name)
+		return call
+	}
+
+	args := json.RawMessage(call.Arguments)
+	if strings.TrimSpace(call.Arguments) == "" {
+		args = json.RawMessage("{}")
+	}
+	result, err := tool.Run(ctx, args)
+	if err != nil {
+		log.Printf("[Tools] %s failed: %v", call.Name, err)
+		call.Error = err.Error()
+		return call
+	}
+
+	if runes := []rune(result); len(runes) > maxToolResultLength {
+		result = string(runes[:maxToolResultLength]) + "\n...(truncated)"
+	}
+	log.Printf("[Tools] %s returned %d chars", call.Name, len(result))
+	call.Result = result
+	return call
+}
+
+// toolOutput is the text sent back to the model for a finished call
+func toolOutput(call ToolCall) string {
+	if call.Error != "" {
+		return "Error: " + call.Error
+	}
+	return call.Result
+}
+
+// toolProvider wraps a provider so its queries run the tool loop
+type toolProvider struct {
+	Provider
+	tools         []Tool
+	maxIterations int
+}
+
+// WithTools returns a provider that lets the model call tools while
+// answering. Providers that cannot call functions are returned unchanged.
+func WithTools(p Provider, tools []Tool, maxIterations int) Provider {
+	if len(tools) == 0 || !p.Capabilities().Tools {
+		return p
+	}
+	if _, ok := p.(ToolCaller); !ok {
+		return p
+	}
+	return &toolProvider{Provider: p, tools: tools, maxIterations: maxIterations}
+}
+
+// ContextWindow reports the window of the wrapped provider
+func (p *toolProvider) ContextWindow() int { return ContextWindow(p.Provider) }
+
+func (p *toolProvider) Query(ctx context.Context, req Request) (*Response, error) {
+	return RunTools(ctx, p.Provider.(ToolCaller), req, p.tools, p.maxIterations)
+}
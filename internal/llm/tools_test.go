@@ -0,0 +1,59 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// loopingCaller asks for the echo tool whenever calls are allowed
+type loopingCaller struct {
+	steps  int
+	rounds []ToolRound
+}
+
+func (c *loopingCaller) QueryWithTools(ctx context.Context, req Request, tools []Tool, rounds []ToolRound, allowCalls bool) (*ToolReply, error) {
+	c.steps++
+	c.rounds = rounds
+	if !allowCalls {
+		return &ToolReply{Text: "final answer", Model: "test"}, nil
+	}
+	return &ToolReply{Calls: []ToolCall{{ID: "1", Name: "echo", Arguments: `{"text":"hi"}`}}}, nil
+}
+
+func TestRunToolsMaxIterations(t *testing.T) {
+	echo := Tool{
+		Name: "echo",
+		Run: func(ctx context.Context, args json.RawMessage) (string, error) {
+			var in struct{ Text string }
+			json.Unmarshal(args, &in)
+			return "echo: " + in.Text, nil
+		},
+	}
+
+	caller := &loopingCaller{}
+	resp, err := RunTools(context.Background(), caller, Request{Query: "q"}, []Tool{echo}, 2)
+	if err != nil {
+		t.Fatalf("RunTools: %v", err)
+	}
+	if resp.Text != "final answer" {
+		t.Errorf("text = %q", resp.Text)
+	}
+	if caller.steps != 3 {
+		t.Errorf("steps = %d, want 2 tool rounds and a final answer", caller.steps)
+	}
+	if len(resp.ToolCalls) != 2 || resp.ToolCalls[0].Result != "echo: hi" {
+		t.Errorf("tool calls = %+v", resp.ToolCalls)
+	}
+	if len(caller.rounds) != 2 {
+		t.Errorf("final step saw %d rounds, want 2", len(caller.rounds))
+	}
+}
+
+func TestRunToolsUnknownTool(t *testing.T) {
+	call := runTool(context.Background(), map[string]Tool{}, ToolCall{Name: "missing"})
+	if !strings.Contains(call.Error, "unknown tool") {
+		t.Errorf("error = %q", call.Error)
+	}
+}
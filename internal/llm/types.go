@@ -10,11 +10,57 @@ type OpenAIRequest struct {
 	FrequencyPenalty float64         `json:"frequency_penalty,omitempty"`
 	PresencePenalty  float64         `json:"presence_penalty,omitempty"`
 	Stop             []string        `json:"stop,omitempty"`
+	Stream           bool            `json:"stream,omitempty"`
+	StreamOptions    *StreamOptions  `json:"stream_options,omitempty"`
+	Tools            []OpenAITool    `json:"tools,omitempty"`
+	ToolChoice       string          `json:"tool_choice,omitempty"`
+
+	ResponseFormat *OpenAIResponseFormat `json:"response_format,omitempty"`
+}
+
+// StreamOptions asks for the usage in the last event of a stream
+type StreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// OpenAIResponseFormat constrains the answer to JSON matching a schema
+type OpenAIResponseFormat struct {
+	Type       string            `json:"type"` // "json_schema"
+	JSONSchema *OpenAIJSONSchema `json:"json_schema,omitempty"`
+}
+
+type OpenAIJSONSchema struct {
+	Name   string                 `json:"name"`
+	Schema map[string]interface{} `json:"schema"`
 }
 
 type OpenAIMessage struct {
-	Role    string          `json:"role"`
-	Content []OpenAIContent `json:"content"`
+	Role       string           `json:"role"`
+	Content    []OpenAIContent  `json:"content"`
+	ToolCalls  []OpenAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+}
+
+// OpenAITool describes a callable function in the request
+type OpenAITool struct {
+	Type     string             `json:"type"`
+	Function OpenAIToolFunction `json:"function"`
+}
+
+type OpenAIToolFunction struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Parameters  map[string]interface{} `json:"parameters,omitempty"`
+}
+
+// OpenAIToolCall is a function call requested by the model
+type OpenAIToolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"` // JSON encoded arguments object
+	} `json:"function"`
 }
 
 type OpenAIContent struct {
@@ -29,6 +75,19 @@ type OpenAIImageURL struct {
 
 type OpenAIResponse struct {
 	Choices []OpenAIChoice `json:"choices"`
+	Usage   *OpenAIUsage   `json:"usage,omitempty"`
+}
+
+type OpenAIUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+func (u *OpenAIUsage) usage() Usage {
+	if u == nil {
+		return Usage{}
+	}
+	return Usage{PromptTokens: u.PromptTokens, CompletionTokens: u.CompletionTokens}
 }
 
 type OpenAIChoice struct {
@@ -36,14 +95,40 @@ type OpenAIChoice struct {
 }
 
 type OpenAIMessageResponse struct {
-	Content string `json:"content"`
+	Content   string           `json:"content"`
+	ToolCalls []OpenAIToolCall `json:"tool_calls,omitempty"`
+	// Reasoning models served by vLLM, DeepSeek and LM Studio return their
+	// thinking separately under one of these names
+	ReasoningContent string `json:"reasoning_content,omitempty"`
+	Reasoning        string `json:"reasoning,omitempty"`
+}
+
+// reasoningText returns the reasoning field the server filled in
+func (m OpenAIMessageResponse) reasoningText() string {
+	if m.ReasoningContent != "" {
+		return m.ReasoningContent
+	}
+	return m.Reasoning
+}
+
+// OpenAIStreamResponse is one server-sent event of a streamed completion
+type OpenAIStreamResponse struct {
+	Choices []OpenAIStreamChoice `json:"choices"`
+	Usage   *OpenAIUsage         `json:"usage,omitempty"` // Sent in the last event by servers that support it
+}
+
+type OpenAIStreamChoice struct {
+	Delta        OpenAIMessageResponse `json:"delta"`
+	FinishReason string                `json:"finish_reason,omitempty"`
 }
 
 // Anthropic API structures
 type AnthropicRequest struct {
 	Model     string             `json:"model"`
 	MaxTokens int                `json:"max_tokens"`
+	System    string             `json:"system,omitempty"`
 	Messages  []AnthropicMessage `json:"messages"`
+	Stream    bool               `json:"stream,omitempty"`
 }
 
 type AnthropicMessage struct {
@@ -64,7 +149,19 @@ type AnthropicImageSource struct {
 }
 
 type AnthropicResponse struct {
-	Content []AnthropicContentResponse `json:"content"`
+	Model      string                     `json:"model"`
+	Content    []AnthropicContentResponse `json:"content"`
+	StopReason string                     `json:"stop_reason"`
+	Usage      AnthropicUsage             `json:"usage"`
+}
+
+type AnthropicUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+func (u AnthropicUsage) usage() Usage {
+	return Usage{PromptTokens: u.InputTokens, CompletionTokens: u.OutputTokens}
 }
 
 type AnthropicContentResponse struct {
@@ -72,13 +169,39 @@ type AnthropicContentResponse struct {
 	Text string `json:"text"`
 }
 
+// AnthropicStreamEvent is one server-sent event of a streamed message
+type AnthropicStreamEvent struct {
+	Type  string `json:"type"`
+	Delta struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"delta"`
+	Message *struct {
+		Usage AnthropicUsage `json:"usage"`
+	} `json:"message,omitempty"` // Sent with message_start
+	Usage *AnthropicUsage `json:"usage,omitempty"` // Sent with message_delta, counts are cumulative
+	Error *AnthropicError `json:"error,omitempty"`
+}
+
+type AnthropicErrorResponse struct {
+	Type  string         `json:"type"`
+	Error AnthropicError `json:"error"`
+}
+
+type AnthropicError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
 // Gemini API structures
 type GeminiRequest struct {
-	Contents         []GeminiContent         `json:"contents"`
-	GenerationConfig *GeminiGenerationConfig `json:"generationConfig,omitempty"`
+	Contents          []GeminiContent         `json:"contents"`
+	SystemInstruction *GeminiContent          `json:"systemInstruction,omitempty"`
+	GenerationConfig  *GeminiGenerationConfig `json:"generationConfig,omitempty"`
 }
 
 type GeminiContent struct {
+	Role  string       `json:"role,omitempty"`
 	Parts []GeminiPart `json:"parts"`
 }
 
@@ -98,9 +221,27 @@ type GeminiGenerationConfig struct {
 }
 
 type GeminiResponse struct {
-	Candidates []GeminiCandidate `json:"candidates"`
+	Candidates     []GeminiCandidate     `json:"candidates"`
+	PromptFeedback *GeminiPromptFeedback `json:"promptFeedback,omitempty"`
+	UsageMetadata  struct {
+		PromptTokenCount     int `json:"promptTokenCount"`
+		CandidatesTokenCount int `json:"candidatesTokenCount"`
+	} `json:"usageMetadata"`
 }
 
 type GeminiCandidate struct {
-	Content GeminiContent `json:"content"`
+	Content      GeminiContent `json:"content"`
+	FinishReason string        `json:"finishReason,omitempty"`
+}
+
+type GeminiPromptFeedback struct {
+	BlockReason string `json:"blockReason,omitempty"`
+}
+
+type GeminiErrorResponse struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Status  string `json:"status"`
+	} `json:"error"`
 }
@@ -41,15 +41,34 @@ func TestCleanResponseText(t *testing.T) {
 			expected: "analysisassistantfinal", // Should return original if nothing left
 		},
 		{
+			// Behavior change: planning sentences are no longer cut without a
+			// gpt-oss channel marker, since they can be part of a real answer
 			name:     "Reasoning with period",
 			input:    "User asks a question. Need to respond clearly. Hello, I'm here to help!",
-			expected: "Hello, I'm here to help!",
+			expected: "User asks a question. Need to respond clearly. Hello, I'm here to help!",
 		},
 		{
+			// Behavior change: as above, kept whole without a channel marker
 			name:     "Chinese response with English reasoning",
 			input:    "Need to respond in Chinese.你好！很高興見到你。",
+			expected: "Need to respond in Chinese.你好！很高興見到你。",
+		},
+		{
+			name:     "Reasoning with period after analysis marker",
+			input:    "analysisUser asks a question. Need to respond clearly. Hello, I'm here to help!",
+			expected: "Hello, I'm here to help!",
+		},
+		{
+			name:     "Chinese response with English reasoning after analysis marker",
+			input:    "analysisNeed to respond in Chinese.你好！很高興見到你。",
 			expected: "你好！很高興見到你。",
 		},
+		{
+			// Without a channel marker planning-like sentences may be the answer
+			name:     "Planning sentences without marker",
+			input:    "Need to respond in Chinese? Set the language in Settings.",
+			expected: "Need to respond in Chinese? Set the language in Settings.",
+		},
 	}
 
 	for _, tt := range tests {
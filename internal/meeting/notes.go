@@ -0,0 +1,169 @@
+package meeting
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Kelen/Korner/internal/llm"
+	"github.com/Kelen/Korner/internal/prompts"
+)
+
+// Notes is a meeting summary the model returns as JSON
+type Notes struct {
+	Topic       string       `json:"topic"`
+	Discussions []Discussion `json:"discussions"`
+	Decisions   []string     `json:"decisions"`
+	ActionItems []ActionItem `json:"actionItems"`
+	Risks       []string     `json:"risks"`
+	Conclusion  string       `json:"conclusion"`
+	NextSteps   string       `json:"nextSteps"`
+}
+
+// Discussion is one topic talked about in the meeting
+type Discussion struct {
+	Topic     string   `json:"topic"`
+	Content   string   `json:"content"`
+	KeyPoints []string `json:"keyPoints"`
+}
+
+// ActionItem is a task agreed on in the meeting. Fields the meeting did not
+// mention are empty.
+type ActionItem struct {
+	Item     string `json:"item"`
+	Owner    string `json:"owner"`
+	Due      string `json:"due"`
+	Priority string `json:"priority"`
+}
+
+// NotesSchema returns the JSON Schema of Notes for llm.QueryJSON
+func NotesSchema() llm.JSONSchema {
+	str := map[string]interface{}{"type": "string"}
+	strList := map[string]interface{}{"type": "array", "items": str}
+	object := func(properties map[string]interface{}) map[string]interface{} {
+		required := make([]string, 0, len(properties))
+		for name := range properties {
+			required = append(required, name)
+		}
+		sort.Strings(required)
+		return map[string]interface{}{
+			"type":                 "object",
+			"properties":           properties,
+			"required":             required,
+			"additionalProperties": false,
+		}
+	}
+
+	return llm.JSONSchema{
+		Name: "meeting_notes",
+		Schema: object(map[string]interface{}{
+			"topic": str,
+			"discussions": map[string]interface{}{
+				"type": "array",
+				"items": object(map[string]interface{}{
+					"topic":     str,
+					"content":   str,
+					"keyPoints": strList,
+				}),
+			},
+			"decisions": strList,
+			"actionItems": map[string]interface{}{
+				"type": "array",
+				"items": object(map[string]interface{}{
+					"item":     str,
+					"owner":    str,
+					"due":      str,
+					"priority": str,
+				}),
+			},
+			"risks":      strList,
+			"conclusion": str,
+			"nextSteps":  str,
+		}),
+	}
+}
+
+// NotesPrompt generates the prompt asking for Notes as JSON
+func NotesPrompt(language string, transcription string) string {
+	data := prompts.NewData(language)
+	data.Transcription = transcription
+	return prompts.Render(prompts.MeetingNotes, data)
+}
+
+// Format renders the notes as plain text for copying and the history
+func (n *Notes) Format(language string) string {
+	zh := language == "zh-TW" || language == "zh"
+	label := func(chinese, english string) string {
+		if zh {
+			return chinese
+		}
+		return english
+	}
+	none := label("無", "None")
+
+	var b strings.Builder
+	section := func(title string) {
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(title + "\n" + strings.Repeat("-", 20) + "\n")
+	}
+	list := func(items []string) {
+		if len(items) == 0 {
+			b.WriteString("- " + none + "\n")
+		}
+		for _, item := range items {
+			b.WriteString("- " + item + "\n")
+		}
+	}
+
+	section(label("會議主題", "Meeting Topic"))
+	b.WriteString(n.Topic + "\n")
+
+	section(label("主要討論內容", "Main Discussion Points"))
+	if len(n.Discussions) == 0 {
+		b.WriteString(none + "\n")
+	}
+	for i, d := range n.Discussions {
+		fmt.Fprintf(&b, "%d. %s\n", i+1, d.Topic)
+		if d.Content != "" {
+			fmt.Fprintf(&b, "   %s\n", d.Content)
+		}
+		for _, point := range d.KeyPoints {
+			fmt.Fprintf(&b, "   - %s\n", point)
+		}
+	}
+
+	section(label("重要決議與共識", "Decisions & Consensus"))
+	list(n.Decisions)
+
+	section(label("行動項目", "Action Items"))
+	if len(n.ActionItems) == 0 {
+		b.WriteString(label("本次會議未產生明確的行動項目", "No clear action items were generated in this meeting") + "\n")
+	}
+	for i, item := range n.ActionItems {
+		fmt.Fprintf(&b, "%d. %s", i+1, item.Item)
+		var details []string
+		if item.Owner != "" {
+			details = append(details, label("負責人：", "Owner: ")+item.Owner)
+		}
+		if item.Due != "" {
+			details = append(details, label("期限：", "Due: ")+item.Due)
+		}
+		if item.Priority != "" {
+			details = append(details, label("優先級：", "Priority: ")+item.Priority)
+		}
+		if len(details) > 0 {
+			fmt.Fprintf(&b, " (%s)", strings.Join(details, ", "))
+		}
+		b.WriteString("\n")
+	}
+
+	section(label("待解決問題與風險", "Pending Issues & Risks"))
+	list(n.Risks)
+
+	section(label("關鍵結論與下一步", "Key Conclusions & Next Steps"))
+	fmt.Fprintf(&b, "%s%s\n", label("會議核心結論：", "Core Conclusion: "), n.Conclusion)
+	fmt.Fprintf(&b, "%s%s\n", label("下一步行動：", "Next Actions: "), n.NextSteps)
+	return b.String()
+}
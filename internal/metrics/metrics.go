@@ -0,0 +1,246 @@
+// Package metrics records the latency, token usage and failures of every
+// model, OCR and transcription request in a local store for the usage
+// dashboard.
+package metrics
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Kinds of recorded requests
+const (
+	KindLLM        = "llm"
+	KindOCR        = "ocr"
+	KindTranscribe = "transcribe"
+)
+
+// Error classes, see ClassifyError
+const (
+	ErrorCancelled = "cancelled"
+	ErrorTimeout   = "timeout"
+	ErrorNetwork   = "network"
+	ErrorRateLimit = "rate_limit"
+	ErrorAuth      = "auth"
+	ErrorServer    = "server"
+	ErrorClient    = "client"
+	ErrorOther     = "other"
+)
+
+// Record is one finished request
+type Record struct {
+	Time             time.Time `json:"time"`
+	Kind             string    `json:"kind"`
+	Provider         string    `json:"provider"`
+	Model            string    `json:"model,omitempty"`
+	LatencyMs        int64     `json:"latencyMs"`
+	PromptTokens     int       `json:"promptTokens,omitempty"`
+	CompletionTokens int       `json:"completionTokens,omitempty"`
+	Estimated        bool      `json:"estimated,omitempty"` // Token counts were estimated, the server sent none
+	Cached           bool      `json:"cached,omitempty"`    // Answered from the response cache
+	ErrorClass       string    `json:"errorClass,omitempty"`
+}
+
+// Store appends records to one JSON Lines file per month. A nil *Store
+// drops records.
+type Store struct {
+	mu  sync.Mutex
+	dir string
+}
+
+// DefaultDir returns ~/.korner/metrics
+func DefaultDir() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		log.Printf("[Metrics] Failed to get home directory: %v", err)
+		return "metrics"
+	}
+	return filepath.Join(homeDir, ".korner", "metrics")
+}
+
+// Open returns a store writing to dir
+func Open(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("create metrics directory: %w", err)
+	}
+	return &Store{dir: dir}, nil
+}
+
+// Add appends r to the store
+func (s *Store) Add(r Record) error {
+	if s == nil {
+		return nil
+	}
+	if r.Time.IsZero() {
+		r.Time = time.Now()
+	}
+	line, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("encode record: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.file(r.Time), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("open metrics file: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("write record: %w", err)
+	}
+	return nil
+}
+
+// Observe records a request of kind that started at start and ended with
+// err. Failures to write are only logged, metrics never break a request.
+func (s *Store) Observe(kind string, provider string, model string, start time.Time, err error) {
+	if s == nil {
+		return
+	}
+	r := Record{
+		Time:       start,
+		Kind:       kind,
+		Provider:   provider,
+		Model:      model,
+		LatencyMs:  time.Since(start).Milliseconds(),
+		ErrorClass: ClassifyError(err),
+	}
+	if err := s.Add(r); err != nil {
+		log.Printf("[Metrics] Warning: %v", err)
+	}
+}
+
+// Load returns the records at or after since, oldest first
+func (s *Store) Load(since time.Time) ([]Record, error) {
+	if s == nil {
+		return nil, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	files, err := filepath.Glob(filepath.Join(s.dir, "*.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	// Month files named before the month of since hold nothing newer
+	first := filepath.Base(s.file(since))
+	var records []Record
+	for _, path := range files {
+		if !since.IsZero() && filepath.Base(path) < first {
+			continue
+		}
+		loaded, err := readFile(path, since)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, loaded...)
+	}
+	sort.SliceStable(records, func(i, j int) bool { return records[i].Time.Before(records[j].Time) })
+	return records, nil
+}
+
+func readFile(path string, since time.Time) ([]Record, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open metrics file: %w", err)
+	}
+	defer f.Close()
+
+	var records []Record
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var r Record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			continue // A line cut short by a crash
+		}
+		if r.Time.Before(since) {
+			continue
+		}
+		records = append(records, r)
+	}
+	return records, scanner.Err()
+}
+
+func (s *Store) file(t time.Time) string {
+	return filepath.Join(s.dir, t.Format("2006-01")+".jsonl")
+}
+
+// statusCodePattern finds the HTTP status in errors like "API error (429): ..."
+var statusCodePattern = regexp.MustCompile(`\((\d{3})\)`)
+
+// ClassifyError sorts err into one of the Error* classes, or "" for nil
+func ClassifyError(err error) string {
+	if err == nil {
+		return ""
+	}
+	if errors.Is(err, context.Canceled) {
+		return ErrorCancelled
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrorTimeout
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		if netErr.Timeout() {
+			return ErrorTimeout
+		}
+		return ErrorNetwork
+	}
+
+	// Provider errors carry their status, messages only sometimes show it
+	var statusErr interface{ HTTPStatus() int }
+	if errors.As(err, &statusErr) {
+		if class := classifyStatus(statusErr.HTTPStatus()); class != "" {
+			return class
+		}
+	}
+	msg := err.Error()
+	if m := statusCodePattern.FindStringSubmatch(msg); m != nil {
+		code, _ := strconv.Atoi(m[1])
+		if class := classifyStatus(code); class != "" {
+			return class
+		}
+	}
+	lower := strings.ToLower(msg)
+	switch {
+	case strings.Contains(lower, "timeout"):
+		return ErrorTimeout
+	case strings.Contains(lower, "connection refused"), strings.Contains(lower, "no such host"),
+		strings.Contains(lower, "http request failed"):
+		return ErrorNetwork
+	}
+	return ErrorOther
+}
+
+// classifyStatus sorts an HTTP error status into an Error* class, or ""
+// when it is not an error status
+func classifyStatus(code int) string {
+	switch {
+	case code == 429:
+		return ErrorRateLimit
+	case code == 401 || code == 403:
+		return ErrorAuth
+	case code >= 500 && code < 600:
+		return ErrorServer
+	case code >= 400 && code < 500:
+		return ErrorClient
+	}
+	return ""
+}
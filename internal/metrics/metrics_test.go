@@ -0,0 +1,112 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestStoreAddLoad(t *testing.T) {
+	s, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	old := Record{Time: now.AddDate(0, -2, 0), Kind: KindLLM, Provider: "ollama", LatencyMs: 100}
+	recent := Record{Time: now.Add(-time.Hour), Kind: KindOCR, Provider: "ollama", LatencyMs: 200}
+	for _, r := range []Record{recent, old} {
+		if err := s.Add(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	all, err := s.Load(time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 2 || all[0].LatencyMs != 100 {
+		t.Fatalf("Load returned %+v, want both records oldest first", all)
+	}
+
+	week, err := s.Load(RangeStart(RangeWeek, now))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(week) != 1 || week[0].Kind != KindOCR {
+		t.Errorf("week range returned %+v", week)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	start := time.Date(2026, 1, 2, 10, 0, 0, 0, time.Local)
+	var records []Record
+	for i := 1; i <= 20; i++ {
+		records = append(records, Record{
+			Time: start, Kind: KindLLM, Provider: "openai", Model: "gpt-4o",
+			LatencyMs: int64(i * 100), PromptTokens: 10, CompletionTokens: 5,
+		})
+	}
+	records = append(records,
+		Record{Time: start, Kind: KindLLM, Provider: "openai", Model: "gpt-4o", Cached: true, PromptTokens: 0},
+		Record{Time: start.AddDate(0, 0, 1), Kind: KindLLM, Provider: "ollama", Model: "qwen3-vl:4b", LatencyMs: 50, ErrorClass: ErrorNetwork},
+	)
+
+	s := Summarize(records, RangeAll)
+	if s.Requests != 22 || s.Errors != 1 || s.CacheHits != 1 {
+		t.Errorf("totals = %d requests, %d errors, %d cache hits", s.Requests, s.Errors, s.CacheHits)
+	}
+	if s.PromptTokens != 200 || s.CompletionTokens != 100 {
+		t.Errorf("tokens = %d/%d", s.PromptTokens, s.CompletionTokens)
+	}
+	if s.ErrorClasses[ErrorNetwork] != 1 {
+		t.Errorf("error classes = %v", s.ErrorClasses)
+	}
+	if len(s.Daily) != 2 || s.Daily[0].Requests != 21 {
+		t.Errorf("daily = %+v", s.Daily)
+	}
+
+	g := s.Groups[0]
+	if g.Provider != "openai" || g.Requests != 21 {
+		t.Fatalf("busiest group = %+v", g)
+	}
+	if g.AvgLatencyMs != 1050 || g.P95LatencyMs != 1900 {
+		t.Errorf("latency avg %d p95 %d, want 1050 and 1900", g.AvgLatencyMs, g.P95LatencyMs)
+	}
+}
+
+// statusError stands in for the status errors of the llm and ocr packages
+type statusError struct {
+	code int
+	msg  string
+}
+
+func (e *statusError) Error() string   { return e.msg }
+func (e *statusError) HTTPStatus() int { return e.code }
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{nil, ""},
+		{fmt.Errorf("http request failed: %w", context.Canceled), ErrorCancelled},
+		{context.DeadlineExceeded, ErrorTimeout},
+		{&net.OpError{Op: "dial", Err: errors.New("connection refused")}, ErrorNetwork},
+		{errors.New("API error (429): slow down"), ErrorRateLimit},
+		{errors.New("API error (401): bad key"), ErrorAuth},
+		{errors.New("API error (503): overloaded"), ErrorServer},
+		{errors.New("API error (400): bad request"), ErrorClient},
+		{errors.New("empty response from API"), ErrorOther},
+		{fmt.Errorf("gemini: %w", &statusError{429, "Resource has been exhausted"}), ErrorRateLimit},
+		// The status wins over a number in parentheses in the message
+		{&statusError{529, "Overloaded (see docs, code (404))"}, ErrorServer},
+	}
+	for _, tt := range tests {
+		if got := ClassifyError(tt.err); got != tt.want {
+			t.Errorf("ClassifyError(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package metrics
+
+import (
+	"sort"
+	"time"
+)
+
+// Ranges accepted by RangeStart
+const (
+	RangeDay   = "day"
+	RangeWeek  = "week"
+	RangeMonth = "month"
+	RangeAll   = "all"
+)
+
+// Summary aggregates the records of a time range for the dashboard
+type Summary struct {
+	Range            string         `json:"range"`
+	Requests         int            `json:"requests"`
+	Errors           int            `json:"errors"`
+	CacheHits        int            `json:"cacheHits"`
+	PromptTokens     int            `json:"promptTokens"`
+	CompletionTokens int            `json:"completionTokens"`
+	Groups           []Group        `json:"groups"`       // Per kind, provider and model, busiest first
+	ErrorClasses     map[string]int `json:"errorClasses"` // Failures per ClassifyError class
+	Daily            []Day          `json:"daily"`        // Oldest first
+}
+
+// Group aggregates the requests of one kind, provider and model
+type Group struct {
+	Kind             string `json:"kind"`
+	Provider         string `json:"provider"`
+	Model            string `json:"model"`
+	Requests         int    `json:"requests"`
+	Errors           int    `json:"errors"`
+	CacheHits        int    `json:"cacheHits"`
+	AvgLatencyMs     int64  `json:"avgLatencyMs"` // Successful requests that reached the model
+	P95LatencyMs     int64  `json:"p95LatencyMs"`
+	PromptTokens     int    `json:"promptTokens"`
+	CompletionTokens int    `json:"completionTokens"`
+	Estimated        bool   `json:"estimated"` // Some token counts were estimated
+}
+
+// Day counts the requests of one local calendar day
+type Day struct {
+	Date     string `json:"date"` // 2006-01-02
+	Requests int    `json:"requests"`
+	Errors   int    `json:"errors"`
+	Tokens   int    `json:"tokens"`
+}
+
+// RangeStart returns when the named range begins relative to now. Unknown
+// names and RangeAll return the zero time.
+func RangeStart(name string, now time.Time) time.Time {
+	switch name {
+	case RangeDay:
+		return now.Add(-24 * time.Hour)
+	case RangeWeek:
+		return now.AddDate(0, 0, -7)
+	case RangeMonth:
+		return now.AddDate(0, -1, 0)
+	}
+	return time.Time{}
+}
+
+// Summary loads and aggregates the records of the named range
+func (s *Store) Summary(rangeName string) (*Summary, error) {
+	records, err := s.Load(RangeStart(rangeName, time.Now()))
+	if err != nil {
+		return nil, err
+	}
+	return Summarize(records, rangeName), nil
+}
+
+// Summarize aggregates records, which must be sorted oldest first
+func Summarize(records []Record, rangeName string) *Summary {
+	summary := &Summary{Range: rangeName, Groups: []Group{}, ErrorClasses: map[string]int{}, Daily: []Day{}}
+
+	type key struct{ kind, provider, model string }
+	groups := make(map[key]*Group)
+	latencies := make(map[key][]int64)
+	days := make(map[string]*Day)
+
+	for _, r := range records {
+		k := key{r.Kind, r.Provider, r.Model}
+		g, ok := groups[k]
+		if !ok {
+			g = &Group{Kind: r.Kind, Provider: r.Provider, Model: r.Model}
+			groups[k] = g
+		}
+
+		date := r.Time.Local().Format("2006-01-02")
+		day, ok := days[date]
+		if !ok {
+			day = &Day{Date: date}
+			days[date] = day
+		}
+
+		summary.Requests++
+		g.Requests++
+		day.Requests++
+		tokens := r.PromptTokens + r.CompletionTokens
+		summary.PromptTokens += r.PromptTokens
+		summary.CompletionTokens += r.CompletionTokens
+		g.PromptTokens += r.PromptTokens
+		g.CompletionTokens += r.CompletionTokens
+		day.Tokens += tokens
+		if r.Estimated {
+			g.Estimated = true
+		}
+
+		switch {
+		case r.ErrorClass != "":
+			summary.Errors++
+			g.Errors++
+			day.Errors++
+			summary.ErrorClasses[r.ErrorClass]++
+		case r.Cached:
+			summary.CacheHits++
+			g.CacheHits++
+		default:
+			latencies[k] = append(latencies[k], r.LatencyMs)
+		}
+	}
+
+	for k, g := range groups {
+		g.AvgLatencyMs, g.P95LatencyMs = latencyStats(latencies[k])
+		summary.Groups = append(summary.Groups, *g)
+	}
+	sort.Slice(summary.Groups, func(i, j int) bool {
+		a, b := summary.Groups[i], summary.Groups[j]
+		if a.Requests != b.Requests {
+			return a.Requests > b.Requests
+		}
+		return a.Provider+a.Model < b.Provider+b.Model
+	})
+
+	for _, day := range days {
+		summary.Daily = append(summary.Daily, *day)
+	}
+	sort.Slice(summary.Daily, func(i, j int) bool { return summary.Daily[i].Date < summary.Daily[j].Date })
+	return summary
+}
+
+// latencyStats returns the mean and the 95th percentile
+func latencyStats(latencies []int64) (int64, int64) {
+	if len(latencies) == 0 {
+		return 0, 0
+	}
+	sorted := append([]int64(nil), latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var total int64
+	for _, l := range sorted {
+		total += l
+	}
+	index := (len(sorted)*95+99)/100 - 1
+	return total / int64(len(sorted)), sorted[index]
+}
@@ -0,0 +1,299 @@
+package ocr
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// OllamaChatMessage is one message of an /api/chat conversation
+type OllamaChatMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	Images    []string         `json:"images,omitempty"`
+	ToolCalls []OllamaToolCall `json:"tool_calls,omitempty"`
+	ToolName  string           `json:"tool_name,omitempty"` // Set on role "tool" results
+}
+
+// OllamaChatRequest represents the request to Ollama /api/chat
+type OllamaChatRequest struct {
+	Model    string              `json:"model"`
+	Messages []OllamaChatMessage `json:"messages"`
+	Stream   bool                `json:"stream"`
+	Tools    []OllamaTool        `json:"tools,omitempty"`
+	Format   interface{}         `json:"format,omitempty"` // JSON Schema the answer must match
+}
+
+// OllamaTool describes a function the model may call
+type OllamaTool struct {
+	Type     string             `json:"type"`
+	Function OllamaToolFunction `json:"function"`
+}
+
+type OllamaToolFunction struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Parameters  map[string]interface{} `json:"parameters,omitempty"`
+}
+
+// OllamaToolCall is a function call requested by the model. Unlike OpenAI,
+// Ollama sends the arguments as a JSON object.
+type OllamaToolCall struct {
+	Function struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	} `json:"function"`
+}
+
+// OllamaChatResponse represents one response object from Ollama /api/chat
+type OllamaChatResponse struct {
+	Model   string            `json:"model"`
+	Message OllamaChatMessage `json:"message"`
+	Done    bool              `json:"done"`
+	Error   string            `json:"error,omitempty"` // Set when the model fails, also mid-stream
+	// Token counts, sent with the final object
+	PromptEvalCount int `json:"prompt_eval_count,omitempty"`
+	EvalCount       int `json:"eval_count,omitempty"`
+}
+
+// usage returns the token counts of a final response
+func (r *OllamaChatResponse) usage() Usage {
+	return Usage{PromptTokens: r.PromptEvalCount, CompletionTokens: r.EvalCount}
+}
+
+// ChatOllama sends a multi-turn conversation to Ollama /api/chat. The last
+// message is the new question; the answer rules for language are added to
+// it. When onChunk is not nil the answer is streamed. An empty model uses
+// DefaultModel.
+func ChatOllama(ctx context.Context, messages []OllamaChatMessage, endpoint string, model string, language string, onChunk func(chunk string)) (string, Usage, error) {
+	if endpoint == "" {
+		endpoint = DefaultEndpoint
+	}
+	if model == "" {
+		model = DefaultModel
+	}
+	if len(messages) == 0 {
+		return "", Usage{}, fmt.Errorf("no messages to send")
+	}
+
+	apiURL := strings.TrimSuffix(endpoint, "/") + "/api/chat"
+	log.Printf("[Ollama Chat] Endpoint: %s, model: %s, messages: %d", endpoint, model, len(messages))
+
+	// Strip data URL prefixes and prepend the rules to the new question
+	sent := make([]OllamaChatMessage, len(messages))
+	for i, msg := range messages {
+		images := make([]string, len(msg.Images))
+		for j, img := range msg.Images {
+			images[j] = stripDataURL(img)
+		}
+		msg.Images = images
+		sent[i] = msg
+	}
+	last := &sent[len(sent)-1]
+	last.Content = answerRules(language) + last.Content
+
+	body, err := json.Marshal(OllamaChatRequest{
+		Model:    model,
+		Messages: sent,
+		Stream:   onChunk != nil,
+	})
+	if err != nil {
+		return "", Usage{}, fmt.Errorf("marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL, bytes.NewReader(body))
+	if err != nil {
+		return "", Usage{}, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := newLocalClient(180 * time.Second).Do(req)
+	if err != nil {
+		return "", Usage{}, fmt.Errorf("http request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		log.Printf("[Ollama Chat] ERROR response: %s", string(bodyBytes))
+		return "", Usage{}, &StatusError{StatusCode: resp.StatusCode, Body: string(bodyBytes)}
+	}
+
+	// Non-streamed responses are a single object, streamed ones are NDJSON
+	decoder := json.NewDecoder(resp.Body)
+	var full strings.Builder
+	var usage Usage
+	for {
+		var part OllamaChatResponse
+		if err := decoder.Decode(&part); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return "", Usage{}, fmt.Errorf("decode response: %w", err)
+		}
+		if part.Error != "" {
+			return "", Usage{}, fmt.Errorf("ollama error: %s", part.Error)
+		}
+		if part.Message.Content != "" {
+			full.WriteString(part.Message.Content)
+			if onChunk != nil {
+				onChunk(part.Message.Content)
+			}
+		}
+		if part.Done {
+			usage = part.usage()
+			break
+		}
+	}
+
+	responseText := strings.TrimSpace(full.String())
+	log.Printf("[Ollama Chat] Response length: %d", len(responseText))
+	return responseText, usage, nil
+}
+
+// ChatOllamaTools sends one non-streamed /api/chat step with tools and
+// returns the assistant message, which either holds tool calls or the
+// answer. Unlike ChatOllama the messages are sent as they are, so the
+// caller supplies its own system prompt.
+func ChatOllamaTools(ctx context.Context, messages []OllamaChatMessage, tools []OllamaTool, endpoint string, model string) (*OllamaChatResponse, error) {
+	if endpoint == "" {
+		endpoint = DefaultEndpoint
+	}
+	if model == "" {
+		model = DefaultModel
+	}
+
+	log.Printf("[Ollama Chat] Tool step, model: %s, messages: %d, tools: %d", model, len(messages), len(tools))
+	return sendChat(ctx, endpoint, OllamaChatRequest{
+		Model:    model,
+		Messages: stripImages(messages),
+		Tools:    tools,
+	})
+}
+
+// ChatOllamaJSON sends one non-streamed /api/chat request whose answer is
+// constrained to the JSON Schema in format. Like ChatOllamaTools the
+// messages are sent as they are.
+func ChatOllamaJSON(ctx context.Context, messages []OllamaChatMessage, format map[string]interface{}, endpoint string, model string) (string, Usage, error) {
+	if endpoint == "" {
+		endpoint = DefaultEndpoint
+	}
+	if model == "" {
+		model = DefaultModel
+	}
+
+	log.Printf("[Ollama Chat] JSON request, model: %s, messages: %d", model, len(messages))
+	resp, err := sendChat(ctx, endpoint, OllamaChatRequest{
+		Model:    model,
+		Messages: stripImages(messages),
+		Format:   format,
+	})
+	if err != nil {
+		return "", Usage{}, err
+	}
+	return strings.TrimSpace(resp.Message.Content), resp.usage(), nil
+}
+
+// sendChat posts a non-streamed request to /api/chat
+func sendChat(ctx context.Context, endpoint string, chatReq OllamaChatRequest) (*OllamaChatResponse, error) {
+	apiURL := strings.TrimSuffix(endpoint, "/") + "/api/chat"
+	body, err := json.Marshal(chatReq)
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := newLocalClient(180 * time.Second).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("http request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		log.Printf("[Ollama Chat] ERROR response: %s", string(bodyBytes))
+		return nil, &StatusError{StatusCode: resp.StatusCode, Body: string(bodyBytes)}
+	}
+
+	var result OllamaChatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	if result.Error != "" {
+		return nil, fmt.Errorf("ollama error: %s", result.Error)
+	}
+	return &result, nil
+}
+
+// stripImages returns a copy of messages with data URL prefixes removed
+// from their images
+func stripImages(messages []OllamaChatMessage) []OllamaChatMessage {
+	sent := make([]OllamaChatMessage, len(messages))
+	for i, msg := range messages {
+		images := make([]string, len(msg.Images))
+		for j, img := range msg.Images {
+			images[j] = stripDataURL(img)
+		}
+		msg.Images = images
+		sent[i] = msg
+	}
+	return sent
+}
+
+// stripDataURL removes a data URL prefix from base64 image data
+func stripDataURL(imageBase64 string) string {
+	if strings.HasPrefix(imageBase64, "data:image/") {
+		parts := strings.SplitN(imageBase64, ",", 2)
+		if len(parts) == 2 {
+			return parts[1]
+		}
+	}
+	return imageBase64
+}
+
+// StatusError is an error answer from Ollama's HTTP API
+type StatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("API error (%d): %s", e.StatusCode, strings.TrimSpace(e.Body))
+}
+
+// HTTPStatus lets the llm fallback chain tell an overloaded server from a
+// bad request
+func (e *StatusError) HTTPStatus() int { return e.StatusCode }
+
+// newLocalClient creates an HTTP client that bypasses the proxy for
+// servers on this machine, such as Ollama or a local OCR server
+func newLocalClient(timeout time.Duration) *http.Client {
+	transport := &http.Transport{
+		MaxIdleConns:        10,
+		MaxIdleConnsPerHost: 10,
+		MaxConnsPerHost:     10,
+		Proxy: func(req *http.Request) (*url.URL, error) {
+			host := req.URL.Hostname()
+			if host == "127.0.0.1" || host == "localhost" {
+				return nil, nil
+			}
+			return http.ProxyFromEnvironment(req)
+		},
+	}
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+	}
+}
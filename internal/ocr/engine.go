@@ -0,0 +1,122 @@
+package ocr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// OCR engine names accepted by NewEngine
+const (
+	EngineOllama    = "ollama"    // Vision model on the Ollama server
+	EngineTesseract = "tesseract" // Local tesseract binary
+	EngineHTTP      = "http"      // OCR server speaking the HTTPEngine protocol
+)
+
+// Box is a rectangle in image pixels, measured from the top left corner of
+// the captured region
+type Box struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// Result is the text an engine read from an image, with its layout
+type Result struct {
+	Engine string  `json:"engine"` // The engine that actually read the image
+	Width  int     `json:"width"`  // Image size in pixels, 0 when unknown
+	Height int     `json:"height"`
+	Text   string  `json:"text"` // All text in reading order
+	Blocks []Block `json:"blocks"`
+	Tables []Table `json:"tables"`
+}
+
+// Engine reads the text in an image
+type Engine interface {
+	Name() string
+	// Recognize reads imageBase64, with or without a data URL prefix. An
+	// image without text gives an empty result, not an error.
+	Recognize(ctx context.Context, imageBase64 string) (*Result, error)
+}
+
+// EngineConfig holds the settings of every engine, each uses its own fields
+type EngineConfig struct {
+	OllamaEndpoint string
+	OllamaModel    string
+	TesseractPath  string // Empty searches PATH and the usual install folders
+	Languages      string // Tesseract languages such as "chi_tra+eng"
+	ServerURL      string // OCR server of the http engine
+}
+
+// NewEngine returns the named engine
+func NewEngine(name string, cfg EngineConfig) (Engine, error) {
+	switch name {
+	case EngineOllama, "":
+		return &ollamaEngine{endpoint: cfg.OllamaEndpoint, model: cfg.OllamaModel}, nil
+	case EngineTesseract:
+		return &TesseractEngine{Path: cfg.TesseractPath, Languages: cfg.Languages}, nil
+	case EngineHTTP:
+		if cfg.ServerURL == "" {
+			return nil, errors.New("OCR server URL not configured")
+		}
+		return &HTTPEngine{URL: cfg.ServerURL}, nil
+	}
+	return nil, fmt.Errorf("unknown OCR engine: %s", name)
+}
+
+// ollamaEngine reads text with a vision model through ExtractTextFromImage.
+// VLMs return plain text, so its lines carry no boxes or confidence and
+// tables come from the markdown the model writes.
+type ollamaEngine struct {
+	endpoint string
+	model    string
+}
+
+func (e *ollamaEngine) Name() string { return EngineOllama }
+
+func (e *ollamaEngine) Recognize(ctx context.Context, imageBase64 string) (*Result, error) {
+	text, err := ExtractTextFromImage(ctx, imageBase64, e.endpoint, e.model)
+	if err != nil {
+		return nil, err
+	}
+	result := newResult(EngineOllama, imageBase64, textBlocks(text))
+	// Keep the model's own formatting, such as markdown tables
+	result.Text = text
+	result.Tables = append(result.Tables, parseMarkdownTables(text)...)
+	return result, nil
+}
+
+// fallbackEngine tries its engines in order until one succeeds
+type fallbackEngine struct {
+	engines []Engine
+}
+
+// WithFallback returns an engine that tries primary first and the others
+// in order when it fails, so OCR keeps working while Ollama is down
+func WithFallback(primary Engine, others ...Engine) Engine {
+	if len(others) == 0 {
+		return primary
+	}
+	return &fallbackEngine{engines: append([]Engine{primary}, others...)}
+}
+
+func (e *fallbackEngine) Name() string { return e.engines[0].Name() }
+
+func (e *fallbackEngine) Recognize(ctx context.Context, imageBase64 string) (*Result, error) {
+	var errs []string
+	for _, engine := range e.engines {
+		result, err := engine.Recognize(ctx, imageBase64)
+		if err == nil {
+			return result, nil
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		log.Printf("[OCR] %s failed, trying the next engine: %v", engine.Name(), err)
+		errs = append(errs, fmt.Sprintf("%s: %v", engine.Name(), err))
+	}
+	return nil, fmt.Errorf("all OCR engines failed: %s", strings.Join(errs, "; "))
+}
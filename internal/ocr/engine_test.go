@@ -0,0 +1,122 @@
+package ocr
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const sampleTSV = "level\tpage_num\tblock_num\tpar_num\tline_num\tword_num\tleft\ttop\twidth\theight\tconf\ttext\n" +
+	"1\t1\t0\t0\t0\t0\t0\t0\t400\t100\t-1\t\n" +
+	"4\t1\t1\t1\t1\t0\t10\t10\t200\t20\t-1\t\n" +
+	"5\t1\t1\t1\t1\t2\t70\t10\t60\t20\t90\tWorld\n" +
+	"5\t1\t1\t1\t1\t1\t10\t12\t50\t18\t96\tHello\n" +
+	"5\t1\t1\t1\t2\t1\t10\t40\t20\t20\t80\t會議\n" +
+	"5\t1\t1\t1\t2\t2\t32\t40\t20\t20\t70\t紀錄\n" +
+	"5\t1\t1\t1\t2\t3\t60\t40\t20\t20\t0\t \n"
+
+func TestParseTesseractTSV(t *testing.T) {
+	blocks := parseTesseractTSV(sampleTSV)
+	if len(blocks) != 1 || len(blocks[0].Lines) != 2 {
+		t.Fatalf("got %+v, want one paragraph of two lines", blocks)
+	}
+
+	first := blocks[0].Lines[0]
+	if first.Text != "Hello World" {
+		t.Errorf("first line = %q, want words sorted left to right", first.Text)
+	}
+	if *first.Box != (Box{X: 10, Y: 10, Width: 120, Height: 20}) {
+		t.Errorf("first line box = %+v", *first.Box)
+	}
+	if first.Confidence < 0.92 || first.Confidence > 0.94 {
+		t.Errorf("first line confidence = %v, want the mean 0.93", first.Confidence)
+	}
+	if len(first.Words) != 2 || first.Words[0].Text != "Hello" {
+		t.Errorf("first line words = %+v", first.Words)
+	}
+
+	if blocks[0].Lines[1].Text != "會議紀錄" {
+		t.Errorf("CJK words joined as %q", blocks[0].Lines[1].Text)
+	}
+	if blocks[0].Text != "Hello World\n會議紀錄" || *blocks[0].Box != (Box{X: 10, Y: 10, Width: 120, Height: 50}) {
+		t.Errorf("paragraph = %q %+v", blocks[0].Text, *blocks[0].Box)
+	}
+}
+
+func TestHTTPEngine(t *testing.T) {
+	var got httpOCRRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Write([]byte(`{"blocks":[{"text":"line one","box":{"x":1,"y":2,"width":30,"height":10},"confidence":0.9},{"text":"line two"}]}`))
+	}))
+	defer server.Close()
+
+	engine, err := NewEngine(EngineHTTP, EngineConfig{ServerURL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := engine.Recognize(context.Background(), "data:image/png;base64,aW1hZ2U=")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Image != "aW1hZ2U=" {
+		t.Errorf("server received %q, want the data URL prefix stripped", got.Image)
+	}
+	if result.Text != "line one\nline two" || len(result.Blocks) != 2 || result.Blocks[0].Lines[0].Box.Width != 30 {
+		t.Errorf("result = %+v", result)
+	}
+	if c := result.Blocks[1].Lines[0].Confidence; c != -1 {
+		t.Errorf("line without confidence has %v, want -1", c)
+	}
+}
+
+// stubEngine returns its result or error
+type stubEngine struct {
+	name   string
+	err    error
+	called bool
+}
+
+func (s *stubEngine) Name() string { return s.name }
+
+func (s *stubEngine) Recognize(ctx context.Context, imageBase64 string) (*Result, error) {
+	s.called = true
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &Result{Engine: s.name, Text: "text from " + s.name}, nil
+}
+
+func TestWithFallback(t *testing.T) {
+	down := &stubEngine{name: "ollama", err: errors.New("http request failed: connection refused")}
+	backup := &stubEngine{name: "tesseract"}
+	result, err := WithFallback(down, backup).Recognize(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Engine != "tesseract" || !down.called {
+		t.Errorf("result from %s, want the backup after the primary failed", result.Engine)
+	}
+
+	broken := &stubEngine{name: "tesseract", err: errors.New("tesseract not found")}
+	if _, err := WithFallback(down, broken).Recognize(context.Background(), ""); err == nil {
+		t.Error("expected an error when every engine fails")
+	}
+}
+
+func TestReadOllamaStreamError(t *testing.T) {
+	stream := `{"response":"部分","done":false}
+{"error":"model runner has unexpectedly stopped"}
+`
+	text, _, err := readOllamaStream(strings.NewReader(stream), func(string) {})
+	if err == nil || !strings.Contains(err.Error(), "unexpectedly stopped") {
+		t.Errorf("err = %v, want the model error", err)
+	}
+	if text != "部分" {
+		t.Errorf("text = %q, want what arrived before the error", text)
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !windows
+
+package ocr
+
+import "os/exec"
+
+// hideWindow is only needed on Windows
+func hideWindow(cmd *exec.Cmd) {}
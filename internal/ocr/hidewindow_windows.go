@@ -0,0 +1,14 @@
+package ocr
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// createNoWindow keeps console programs from flashing a window
+const createNoWindow = 0x08000000
+
+// hideWindow stops cmd from opening a console window
+func hideWindow(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true, CreationFlags: createNoWindow}
+}
@@ -0,0 +1,92 @@
+package ocr
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// HTTPEngine sends the image to an OCR server, such as a small PaddleOCR
+// or EasyOCR wrapper. The server receives
+//
+//	POST {URL}  {"image": "<base64>"}
+//
+// and answers with {"text": "...", "blocks": [{"text": "...", "box":
+// {"x": 0, "y": 0, "width": 0, "height": 0}, "confidence": 0.98}]}, one
+// block per line of text, each optionally with its "words". Either field
+// may be left out; text is rebuilt from the blocks.
+type HTTPEngine struct {
+	URL string
+}
+
+// httpOCRRequest is the body sent to the OCR server
+type httpOCRRequest struct {
+	Image string `json:"image"`
+}
+
+// httpOCRResponse is the answer of the OCR server
+type httpOCRResponse struct {
+	Text   string        `json:"text"`
+	Blocks []httpOCRLine `json:"blocks"`
+}
+
+// httpOCRLine is a Line whose confidence is -1 when the server sends none
+type httpOCRLine Line
+
+func (l *httpOCRLine) UnmarshalJSON(data []byte) error {
+	type plain Line
+	line := plain{Confidence: -1}
+	if err := json.Unmarshal(data, &line); err != nil {
+		return err
+	}
+	*l = httpOCRLine(line)
+	return nil
+}
+
+func (e *HTTPEngine) Name() string { return EngineHTTP }
+
+func (e *HTTPEngine) Recognize(ctx context.Context, imageBase64 string) (*Result, error) {
+	body, err := json.Marshal(httpOCRRequest{Image: stripDataURL(imageBase64)})
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	log.Printf("[HTTP OCR] Sending image to %s", e.URL)
+	resp, err := newLocalClient(60 * time.Second).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("http request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("API error (%d): %s", resp.StatusCode, strings.TrimSpace(string(bodyBytes)))
+	}
+
+	var decoded httpOCRResponse
+	if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+
+	lines := make([]Line, len(decoded.Blocks))
+	for i, line := range decoded.Blocks {
+		lines[i] = Line(line)
+	}
+	result := newResult(EngineHTTP, imageBase64, lineBlocks(lines))
+	if text := strings.TrimSpace(decoded.Text); text != "" {
+		result.Text = text
+	}
+	log.Printf("[HTTP OCR] Recognized %d blocks", len(result.Blocks))
+	return result, nil
+}
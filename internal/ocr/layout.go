@@ -0,0 +1,349 @@
+package ocr
+
+import (
+	"encoding/base64"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Word is one word of a line
+type Word struct {
+	Text       string  `json:"text"`
+	Box        Box     `json:"box"`
+	Confidence float64 `json:"confidence"` // 0 to 1
+}
+
+// Line is one line of text. Words are only filled by engines that locate
+// single words.
+type Line struct {
+	Text       string  `json:"text"`
+	Box        *Box    `json:"box,omitempty"` // Nil when the engine cannot locate text
+	Confidence float64 `json:"confidence"`    // 0 to 1, or -1 when the engine gives none
+	Words      []Word  `json:"words,omitempty"`
+}
+
+// Block is a paragraph or another group of lines read together
+type Block struct {
+	Text  string `json:"text"`
+	Box   *Box   `json:"box,omitempty"`
+	Lines []Line `json:"lines"`
+}
+
+// Cell is one cell of a table
+type Cell struct {
+	Text string `json:"text"`
+	Box  *Box   `json:"box,omitempty"`
+}
+
+// Table is a grid found in the image, row by row. Rows may have fewer
+// cells than the widest row when cells are empty.
+type Table struct {
+	Box  *Box     `json:"box,omitempty"`
+	Rows [][]Cell `json:"rows"`
+}
+
+// newResult fills in the image size, the text and the tables of blocks
+func newResult(engine string, imageBase64 string, blocks []Block) *Result {
+	result := &Result{Engine: engine, Blocks: blocks, Tables: detectTables(blocks)}
+	if result.Blocks == nil {
+		result.Blocks = []Block{}
+	}
+	result.Width, result.Height = imageSize(imageBase64)
+
+	texts := make([]string, len(result.Blocks))
+	for i, block := range result.Blocks {
+		texts[i] = block.Text
+	}
+	result.Text = strings.Join(texts, "\n")
+	return result
+}
+
+// imageSize returns the pixel size of a PNG or JPEG image, 0 when unknown
+func imageSize(imageBase64 string) (int, int) {
+	reader := base64.NewDecoder(base64.StdEncoding, strings.NewReader(stripDataURL(imageBase64)))
+	config, _, err := image.DecodeConfig(reader)
+	if err != nil {
+		return 0, 0
+	}
+	return config.Width, config.Height
+}
+
+// newBlock groups lines into a block
+func newBlock(lines []Line) Block {
+	block := Block{Lines: lines}
+	texts := make([]string, len(lines))
+	for i, line := range lines {
+		texts[i] = line.Text
+		if line.Box == nil {
+			continue
+		}
+		if block.Box == nil {
+			box := *line.Box
+			block.Box = &box
+		} else {
+			*block.Box = unionBox(*block.Box, *line.Box)
+		}
+	}
+	block.Text = strings.Join(texts, "\n")
+	return block
+}
+
+// textBlocks splits plain text into paragraphs at blank lines, for
+// engines that give no positions
+func textBlocks(text string) []Block {
+	var blocks []Block
+	var lines []Line
+	flush := func() {
+		if len(lines) > 0 {
+			blocks = append(blocks, newBlock(lines))
+			lines = nil
+		}
+	}
+	for _, row := range strings.Split(text, "\n") {
+		if row = strings.TrimSpace(row); row == "" {
+			flush()
+			continue
+		}
+		lines = append(lines, Line{Text: row, Confidence: -1})
+	}
+	flush()
+	return blocks
+}
+
+// lineBlocks groups lines that sit right below each other into blocks and
+// puts the blocks in reading order. Lines without a box keep their order
+// and form a block each, after the others.
+func lineBlocks(lines []Line) []Block {
+	var boxed, loose []Line
+	for _, line := range lines {
+		if line.Box != nil {
+			boxed = append(boxed, line)
+		} else {
+			loose = append(loose, line)
+		}
+	}
+	sort.SliceStable(boxed, func(i, j int) bool { return boxed[i].Box.Y < boxed[j].Box.Y })
+
+	var groups [][]Line
+	for _, line := range boxed {
+		joined := false
+		for i, group := range groups {
+			if continuesBlock(group[len(group)-1], line) {
+				groups[i] = append(group, line)
+				joined = true
+				break
+			}
+		}
+		if !joined {
+			groups = append(groups, []Line{line})
+		}
+	}
+	// Blocks side by side are read left to right, then downwards
+	sort.SliceStable(groups, func(i, j int) bool {
+		a, b := groups[i][0].Box, groups[j][0].Box
+		if sameRow(a, b) {
+			return a.X < b.X
+		}
+		return a.Y < b.Y
+	})
+
+	blocks := make([]Block, 0, len(lines))
+	for _, group := range groups {
+		blocks = append(blocks, newBlock(group))
+	}
+	for _, line := range loose {
+		blocks = append(blocks, newBlock([]Line{line}))
+	}
+	return blocks
+}
+
+// sameRow reports whether the vertical center of either box falls inside
+// the other
+func sameRow(a *Box, b *Box) bool {
+	centerA, centerB := a.Y+a.Height/2, b.Y+b.Height/2
+	return (centerA >= b.Y && centerA <= b.Y+b.Height) || (centerB >= a.Y && centerB <= a.Y+a.Height)
+}
+
+// continuesBlock reports whether next is the line after prev in the same
+// paragraph: close below it and overlapping it horizontally
+func continuesBlock(prev Line, next Line) bool {
+	a, b := prev.Box, next.Box
+	gap := b.Y - (a.Y + a.Height)
+	overlap := min(a.X+a.Width, b.X+b.Width) - max(a.X, b.X)
+	return gap >= 0 && gap < max(a.Height, b.Height) && overlap > 0
+}
+
+// groupRows sorts boxed lines top to bottom and puts lines whose vertical
+// centers fall on the same row together, left to right
+func groupRows(lines []Line) [][]Line {
+	sorted := append([]Line(nil), lines...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Box.Y < sorted[j].Box.Y })
+
+	var rows [][]Line
+	var top, bottom int
+	for _, line := range sorted {
+		center := line.Box.Y + line.Box.Height/2
+		if len(rows) > 0 && center >= top && center <= bottom {
+			rows[len(rows)-1] = append(rows[len(rows)-1], line)
+			bottom = max(bottom, line.Box.Y+line.Box.Height)
+			continue
+		}
+		rows = append(rows, []Line{line})
+		top, bottom = line.Box.Y, line.Box.Y+line.Box.Height
+	}
+	for _, row := range rows {
+		sort.SliceStable(row, func(i, j int) bool { return row[i].Box.X < row[j].Box.X })
+	}
+	return rows
+}
+
+// maxTableCellRunes keeps two columns of running text from being taken
+// for a table
+const maxTableCellRunes = 40
+
+// detectTables finds runs of rows that split into the same number of
+// columns at the same positions. Cells are lines on the same row, or
+// words of a line separated by a gap wider than the line is tall.
+func detectTables(blocks []Block) []Table {
+	var segments []Line
+	for _, block := range blocks {
+		for _, line := range block.Lines {
+			if line.Box != nil {
+				segments = append(segments, splitCells(line)...)
+			}
+		}
+	}
+
+	tables := []Table{}
+	rows := groupRows(segments)
+	for start := 0; start < len(rows); {
+		end := start + 1
+		if isTableRow(rows[start]) {
+			for end < len(rows) && isTableRow(rows[end]) && columnsAlign(rows[end-1], rows[end]) {
+				end++
+			}
+		}
+		if end-start >= 2 {
+			tables = append(tables, newTable(rows[start:end]))
+		}
+		start = end
+	}
+	return tables
+}
+
+// splitCells splits a line at the wide gaps between its words
+func splitCells(line Line) []Line {
+	if len(line.Words) < 2 {
+		return []Line{line}
+	}
+	var cells []Line
+	var words []Word
+	flush := func() {
+		texts := make([]string, len(words))
+		box := words[0].Box
+		for i, w := range words {
+			texts[i] = w.Text
+			box = unionBox(box, w.Box)
+		}
+		cells = append(cells, Line{Text: joinWords(texts), Box: &box, Confidence: line.Confidence, Words: words})
+		words = nil
+	}
+	for _, word := range line.Words {
+		if len(words) > 0 {
+			last := words[len(words)-1].Box
+			if word.Box.X-(last.X+last.Width) > line.Box.Height {
+				flush()
+			}
+		}
+		words = append(words, word)
+	}
+	flush()
+	return cells
+}
+
+func isTableRow(row []Line) bool {
+	if len(row) < 2 {
+		return false
+	}
+	for _, cell := range row {
+		if utf8.RuneCountInString(cell.Text) > maxTableCellRunes {
+			return false
+		}
+	}
+	return true
+}
+
+// columnsAlign reports whether two rows have the same columns, each cell
+// overlapping the cell above it
+func columnsAlign(above []Line, below []Line) bool {
+	if len(above) != len(below) {
+		return false
+	}
+	for i := range above {
+		a, b := above[i].Box, below[i].Box
+		if min(a.X+a.Width, b.X+b.Width) <= max(a.X, b.X) {
+			return false
+		}
+	}
+	return true
+}
+
+func newTable(rows [][]Line) Table {
+	table := Table{Rows: make([][]Cell, len(rows))}
+	box := *rows[0][0].Box
+	for i, row := range rows {
+		table.Rows[i] = make([]Cell, len(row))
+		for j, line := range row {
+			table.Rows[i][j] = Cell{Text: line.Text, Box: line.Box}
+			box = unionBox(box, *line.Box)
+		}
+	}
+	table.Box = &box
+	return table
+}
+
+// parseMarkdownTables reads the markdown tables a vision model writes when
+// asked to keep table structure
+func parseMarkdownTables(text string) []Table {
+	var tables []Table
+	var rows [][]Cell
+	flush := func() {
+		if len(rows) >= 2 {
+			tables = append(tables, Table{Rows: rows})
+		}
+		rows = nil
+	}
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "|") {
+			flush()
+			continue
+		}
+		cells := strings.Split(strings.Trim(line, "|"), "|")
+		if isMarkdownDivider(cells) {
+			continue
+		}
+		row := make([]Cell, len(cells))
+		for i, cell := range cells {
+			row[i] = Cell{Text: strings.TrimSpace(cell)}
+		}
+		rows = append(rows, row)
+	}
+	flush()
+	return tables
+}
+
+// isMarkdownDivider reports whether cells are the |---|:---:| row under a
+// markdown table header
+func isMarkdownDivider(cells []string) bool {
+	for _, cell := range cells {
+		if strings.Trim(strings.TrimSpace(cell), ":-") != "" || !strings.Contains(cell, "-") {
+			return false
+		}
+	}
+	return true
+}
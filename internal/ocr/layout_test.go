@@ -0,0 +1,91 @@
+package ocr
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/png"
+	"testing"
+)
+
+func boxed(text string, x, y, width, height int) Line {
+	return Line{Text: text, Box: &Box{X: x, Y: y, Width: width, Height: height}, Confidence: 1}
+}
+
+func TestLineBlocksReadingOrder(t *testing.T) {
+	// Given out of order, as some OCR servers return them
+	lines := []Line{
+		boxed("second line", 10, 32, 200, 20),
+		boxed("Title", 10, 0, 80, 20),
+		boxed("footer", 10, 120, 200, 20), // Far below, a new paragraph
+		boxed("caption", 300, 2, 60, 16),
+		{Text: "no box", Confidence: -1},
+	}
+
+	blocks := lineBlocks(lines)
+	var texts []string
+	for _, block := range blocks {
+		texts = append(texts, block.Text)
+	}
+	want := []string{"Title\nsecond line", "caption", "footer", "no box"}
+	if len(texts) != len(want) {
+		t.Fatalf("blocks = %q, want %q", texts, want)
+	}
+	for i := range want {
+		if texts[i] != want[i] {
+			t.Errorf("block %d = %q, want %q", i, texts[i], want[i])
+		}
+	}
+}
+
+func TestDetectTables(t *testing.T) {
+	word := func(text string, x, y int) Word {
+		return Word{Text: text, Box: Box{X: x, Y: y, Width: 40, Height: 20}, Confidence: 1}
+	}
+	row := func(y int, a, b, c string) Line {
+		return wordsLine([]Word{word(a, 0, y), word(b, 100, y), word(c, 200, y)})
+	}
+	blocks := []Block{
+		newBlock([]Line{boxed("A paragraph of running text above the table", 0, 0, 400, 20)}),
+		newBlock([]Line{row(40, "Name", "Qty", "Price"), row(70, "Tea", "2", "40"), row(100, "Cake", "1", "85")}),
+	}
+
+	tables := detectTables(blocks)
+	if len(tables) != 1 {
+		t.Fatalf("got %d tables, want 1: %+v", len(tables), tables)
+	}
+	rows := tables[0].Rows
+	if len(rows) != 3 || len(rows[0]) != 3 || rows[2][2].Text != "85" {
+		t.Errorf("rows = %+v", rows)
+	}
+	if *tables[0].Box != (Box{X: 0, Y: 40, Width: 240, Height: 80}) {
+		t.Errorf("table box = %+v", *tables[0].Box)
+	}
+}
+
+func TestParseMarkdownTables(t *testing.T) {
+	text := "Order summary\n\n| Item | Qty |\n|:-----|---:|\n| Tea | 2 |\n| Cake | 1 |\n\nThanks"
+	tables := parseMarkdownTables(text)
+	if len(tables) != 1 || len(tables[0].Rows) != 3 {
+		t.Fatalf("tables = %+v", tables)
+	}
+	if tables[0].Rows[0][0].Text != "Item" || tables[0].Rows[2][1].Text != "1" {
+		t.Errorf("rows = %+v", tables[0].Rows)
+	}
+}
+
+func TestNewResultImageSize(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 64, 32))); err != nil {
+		t.Fatal(err)
+	}
+	data := "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())
+
+	result := newResult(EngineTesseract, data, textBlocks("one\ntwo\n\nthree"))
+	if result.Width != 64 || result.Height != 32 {
+		t.Errorf("size = %dx%d, want 64x32", result.Width, result.Height)
+	}
+	if len(result.Blocks) != 2 || result.Text != "one\ntwo\nthree" {
+		t.Errorf("blocks = %+v, text = %q", result.Blocks, result.Text)
+	}
+}
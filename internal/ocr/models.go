@@ -0,0 +1,59 @@
+package ocr
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// OllamaTagsResponse represents the response from Ollama /api/tags
+type OllamaTagsResponse struct {
+	Models []OllamaModel `json:"models"`
+}
+
+// OllamaModel is one locally installed model
+type OllamaModel struct {
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+}
+
+// ListModels returns the names of the models installed on the Ollama server
+func ListModels(ctx context.Context, endpoint string) ([]string, error) {
+	if endpoint == "" {
+		endpoint = DefaultEndpoint
+	}
+
+	tagsURL := strings.TrimSuffix(endpoint, "/") + "/api/tags"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, tagsURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+
+	resp, err := newLocalClient(10 * time.Second).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("http request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, &StatusError{StatusCode: resp.StatusCode, Body: string(bodyBytes)}
+	}
+
+	var tags OllamaTagsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tags); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+
+	names := make([]string, 0, len(tags.Models))
+	for _, model := range tags.Models {
+		names = append(names, model.Name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
@@ -8,9 +8,17 @@ import (
 	"io"
 	"log"
 	"net/http"
-	"net/url"
 	"strings"
 	"time"
+
+	"github.com/Kelen/Korner/internal/prompts"
+)
+
+const (
+	// DefaultEndpoint is the local Ollama server
+	DefaultEndpoint = "http://127.0.0.1:11434"
+	// DefaultModel is the vision model used for OCR and chat
+	DefaultModel = "qwen3-vl:4b"
 )
 
 // OllamaRequest represents the request to Ollama API
@@ -27,12 +35,26 @@ type OllamaResponse struct {
 	CreatedAt string `json:"created_at"`
 	Response  string `json:"response"`
 	Done      bool   `json:"done"`
+	Error     string `json:"error,omitempty"` // Set when the model fails, also mid-stream
+	// Token counts, sent with the final object
+	PromptEvalCount int `json:"prompt_eval_count,omitempty"`
+	EvalCount       int `json:"eval_count,omitempty"`
+}
+
+// Usage is the token count Ollama reports for one request
+type Usage struct {
+	PromptTokens     int // prompt_eval_count
+	CompletionTokens int // eval_count
 }
 
-// ExtractTextFromImage uses Ollama's vision model to extract text from an image
-func ExtractTextFromImage(ctx context.Context, imageBase64 string, endpoint string) (string, error) {
+// ExtractTextFromImage uses Ollama's vision model to extract text from an image.
+// An empty model uses DefaultModel.
+func ExtractTextFromImage(ctx context.Context, imageBase64 string, endpoint string, model string) (string, error) {
 	if endpoint == "" {
-		endpoint = "http://127.0.0.1:11434"
+		endpoint = DefaultEndpoint
+	}
+	if model == "" {
+		model = DefaultModel
 	}
 	
 	// Remove data URL prefix if present
@@ -60,16 +82,10 @@ func ExtractTextFromImage(ctx context.Context, imageBase64 string, endpoint stri
 	}
 	
 	// Prompt for OCR
-	prompt := `請仔細觀察這張圖片，並提取圖片中的所有文字內容。
-要求：
-1. 按照原文順序提取所有可見的文字
-2. 保持原有的格式和結構
-3. 如果有表格，請保持表格結構
-4. 只輸出提取的文字，不要添加任何解釋或說明
-5. 如果圖片中沒有文字，請描述圖片內容`
+	prompt := prompts.Render(prompts.OCR, prompts.NewData(""))
 	
 	reqPayload := OllamaRequest{
-		Model:  "qwen3-vl:4b",
+		Model:  model,
 		Prompt: prompt,
 		Images: []string{imageData},
 		Stream: false,
@@ -83,28 +99,8 @@ func ExtractTextFromImage(ctx context.Context, imageBase64 string, endpoint stri
 	log.Printf("[Ollama OCR] Sending request to: %s", apiURL)
 	log.Printf("[Ollama OCR] Image data length: %d bytes", len(imageData))
 	
-	// Create HTTP client that bypasses proxy for localhost
-	transport := &http.Transport{
-		MaxIdleConns:        10,
-		MaxIdleConnsPerHost: 10,
-		MaxConnsPerHost:     10,
-		Proxy: func(req *http.Request) (*url.URL, error) {
-			// Don't use proxy for localhost/127.0.0.1
-			host := req.URL.Host
-			if host == "127.0.0.1:11434" || host == "127.0.0.1" || 
-			   host == "localhost:11434" || host == "localhost" {
-				log.Printf("[Ollama OCR] Bypassing proxy for: %s", host)
-				return nil, nil
-			}
-			// Use system proxy for other requests
-			return http.ProxyFromEnvironment(req)
-		},
-	}
-	
-	httpClient := &http.Client{
-		Timeout:   120 * time.Second,
-		Transport: transport,
-	}
+	// Bypasses the proxy for a local Ollama
+	httpClient := newLocalClient(120 * time.Second)
 	
 	// Retry logic for connection issues
 	var resp *http.Response
@@ -129,10 +125,18 @@ func ExtractTextFromImage(ctx context.Context, imageBase64 string, endpoint stri
 		}
 		
 		log.Printf("[Ollama OCR] Attempt %d failed: %v", attempt, reqErr)
+		if ctx.Err() != nil {
+			// Cancelled by the caller, retrying will not help
+			return "", ctx.Err()
+		}
 		if attempt < maxRetries {
 			waitTime := time.Duration(attempt*2) * time.Second
 			log.Printf("[Ollama OCR] Waiting %v before retry...", waitTime)
-			time.Sleep(waitTime)
+			select {
+			case <-time.After(waitTime):
+			case <-ctx.Done():
+				return "", ctx.Err()
+			}
 		}
 	}
 	
@@ -144,13 +148,16 @@ func ExtractTextFromImage(ctx context.Context, imageBase64 string, endpoint stri
 	if resp.StatusCode != http.StatusOK {
 		bodyBytes, _ := io.ReadAll(resp.Body)
 		log.Printf("[Ollama OCR] ERROR response: %s", string(bodyBytes))
-		return "", fmt.Errorf("API error (%d): %s", resp.StatusCode, string(bodyBytes))
+		return "", &StatusError{StatusCode: resp.StatusCode, Body: string(bodyBytes)}
 	}
 	
 	var result OllamaResponse
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
 		return "", fmt.Errorf("decode response: %w", err)
 	}
+	if result.Error != "" {
+		return "", fmt.Errorf("ollama error: %s", result.Error)
+	}
 	
 	extractedText := strings.TrimSpace(result.Response)
 	log.Printf("[Ollama OCR] Extracted text length: %d", len(extractedText))
@@ -162,42 +169,37 @@ func ExtractTextFromImage(ctx context.Context, imageBase64 string, endpoint stri
 	return extractedText, nil
 }
 
-// QueryOllama queries Ollama with optional image support
-func QueryOllama(ctx context.Context, query string, imageBase64 string, endpoint string, language string) (string, error) {
+// QueryOllama queries Ollama with optional image support.
+// An empty model uses DefaultModel.
+func QueryOllama(ctx context.Context, query string, imageBase64 string, endpoint string, model string, language string) (string, error) {
+	text, _, err := QueryOllamaStream(ctx, query, imageBase64, endpoint, model, language, nil)
+	return text, err
+}
+
+// QueryOllamaStream queries Ollama like QueryOllama and also returns the
+// token usage. When onChunk is not nil the answer is streamed as NDJSON and
+// every partial response is passed to it.
+func QueryOllamaStream(ctx context.Context, query string, imageBase64 string, endpoint string, model string, language string, onChunk func(chunk string)) (string, Usage, error) {
 	if endpoint == "" {
-		endpoint = "http://127.0.0.1:11434"
+		endpoint = DefaultEndpoint
+	}
+	if model == "" {
+		model = DefaultModel
 	}
 
 	apiURL := strings.TrimSuffix(endpoint, "/") + "/api/generate"
-	log.Printf("[Ollama] Endpoint: %s", endpoint)
+	log.Printf("[Ollama] Endpoint: %s, model: %s", endpoint, model)
 	log.Printf("[Ollama] Query: %s", query)
 	log.Printf("[Ollama] Has image: %v", imageBase64 != "")
 
 	// Build prompt based on language
-	prompt := query
-	if language == "zh-TW" || language == "zh" {
-		prompt = `規則：
-1. 純文字，不用 Markdown
-2. 用數字列表（1. 2. 3.）
-3. 空行分段
-4. 請用繁體中文直接回答以下問題：
-
-` + query
-	} else {
-		prompt = `Rules:
-1. Pure text, no Markdown
-2. Use numbered lists (1. 2. 3.)
-3. Empty line breaks
-4. Please answer the following question directly:
-
-` + query
-	}
+	prompt := answerRules(language) + query
 
 	// Prepare request with optional image
 	reqPayload := OllamaRequest{
-		Model:  "qwen3-vl:4b",
+		Model:  model,
 		Prompt: prompt,
-		Stream: false,
+		Stream: onChunk != nil,
 	}
 
 	// Add image if provided
@@ -216,339 +218,87 @@ func QueryOllama(ctx context.Context, query string, imageBase64 string, endpoint
 
 	body, err := json.Marshal(reqPayload)
 	if err != nil {
-		return "", fmt.Errorf("marshal request: %w", err)
+		return "", Usage{}, fmt.Errorf("marshal request: %w", err)
 	}
 
 	log.Printf("[Ollama] Sending request to: %s", apiURL)
 
-	// Create HTTP client that bypasses proxy for localhost
-	transport := &http.Transport{
-		MaxIdleConns:        10,
-		MaxIdleConnsPerHost: 10,
-		MaxConnsPerHost:     10,
-		Proxy: func(req *http.Request) (*url.URL, error) {
-			host := req.URL.Host
-			if host == "127.0.0.1:11434" || host == "127.0.0.1" ||
-				host == "localhost:11434" || host == "localhost" {
-				return nil, nil
-			}
-			return http.ProxyFromEnvironment(req)
-		},
-	}
-
-	httpClient := &http.Client{
-		Timeout:   180 * time.Second,
-		Transport: transport,
-	}
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL, bytes.NewReader(body))
 	if err != nil {
-		return "", fmt.Errorf("create request: %w", err)
+		return "", Usage{}, fmt.Errorf("create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := httpClient.Do(req)
+	resp, err := newLocalClient(180 * time.Second).Do(req)
 	if err != nil {
-		return "", fmt.Errorf("http request failed: %w", err)
+		return "", Usage{}, fmt.Errorf("http request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		bodyBytes, _ := io.ReadAll(resp.Body)
 		log.Printf("[Ollama] ERROR response: %s", string(bodyBytes))
-		return "", fmt.Errorf("API error (%d): %s", resp.StatusCode, string(bodyBytes))
-	}
-
-	var result OllamaResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", fmt.Errorf("decode response: %w", err)
-	}
-
-	responseText := strings.TrimSpace(result.Response)
-	log.Printf("[Ollama] Response length: %d", len(responseText))
-
-	return responseText, nil
-}
-
-
-// QueryOllamaWithWebSearch queries Ollama with web search results (when web search is enabled)
-func QueryOllamaWithWebSearch(ctx context.Context, query string, endpoint string, language string) (string, error) {
-	if endpoint == "" {
-		endpoint = "http://127.0.0.1:11434"
-	}
-
-	log.Printf("[Ollama+WebSearch] Query: %s", query)
-
-	// Step 1: Perform web search
-	searchResults, err := WebSearch(ctx, query)
-	if err != nil {
-		log.Printf("[Ollama+WebSearch] Web search failed: %v", err)
-		searchResults = nil
-	}
-
-	// Step 2: Format search results
-	searchContext := FormatSearchResultsForLLM(searchResults, language)
-	currentTime := time.Now().Format("2006年1月2日 15:04")
-
-	// Step 3: Build prompt with search context
-	var prompt string
-	if language == "zh-TW" || language == "zh" {
-		prompt = fmt.Sprintf(`你是台灣的 AI 助手。現在時間：%s（UTC+8）
-
-網路搜尋結果：
-%s
-
-規則：純文字、數字列表、繁體中文、不提其他國家
-如果搜尋結果不足，說「搜尋結果有限」
-
-問題：%s`, currentTime, searchContext, query)
-	} else {
-		prompt = fmt.Sprintf(`You are an AI assistant. Current time: %s (UTC+8)
-
-Search results:
-%s
-
-Rules: plain text, numbered lists, don't mention unrelated regions
-If results insufficient, say so
-
-Question: %s`, currentTime, searchContext, query)
-	}
-
-	// Step 4: Send to Ollama
-	apiURL := strings.TrimSuffix(endpoint, "/") + "/api/generate"
-	reqPayload := OllamaRequest{
-		Model:  "qwen3-vl:4b",
-		Prompt: prompt,
-		Stream: false,
-	}
-
-	body, _ := json.Marshal(reqPayload)
-
-	transport := &http.Transport{
-		Proxy: func(req *http.Request) (*url.URL, error) {
-			host := req.URL.Host
-			if strings.HasPrefix(host, "127.0.0.1") || strings.HasPrefix(host, "localhost") {
-				return nil, nil
-			}
-			return http.ProxyFromEnvironment(req)
-		},
-	}
-	httpClient := &http.Client{Timeout: 180 * time.Second, Transport: transport}
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL, bytes.NewReader(body))
-	if err != nil {
-		return "", fmt.Errorf("create request: %w", err)
-	}
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := httpClient.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("http request failed: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("API error (%d): %s", resp.StatusCode, string(bodyBytes))
-	}
-
-	var result OllamaResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", fmt.Errorf("decode response: %w", err)
+		return "", Usage{}, &StatusError{StatusCode: resp.StatusCode, Body: string(bodyBytes)}
 	}
 
-	return strings.TrimSpace(result.Response), nil
-}
-
-// WebSearch performs a web search using DuckDuckGo HTML search for better results
-func WebSearch(ctx context.Context, query string) (*WebSearchResponse, error) {
-	log.Printf("[WebSearch] Searching for: %s", query)
-
-	// Use DuckDuckGo HTML lite version for better search results
-	searchURL := fmt.Sprintf("https://html.duckduckgo.com/html/?q=%s", url.QueryEscape(query))
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, searchURL, nil)
-	if err != nil {
-		return nil, fmt.Errorf("create request: %w", err)
-	}
-
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
-	req.Header.Set("Accept", "text/html,application/xhtml+xml")
-	req.Header.Set("Accept-Language", "zh-TW,zh;q=0.9,en;q=0.8")
-
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("http request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("read response: %w", err)
-	}
-
-	htmlContent := string(body)
-	results := parseHTMLSearchResults(htmlContent)
-
-	log.Printf("[WebSearch] Found %d results", len(results))
-
-	return &WebSearchResponse{
-		Query:   query,
-		Results: results,
-	}, nil
-}
-
-// parseHTMLSearchResults extracts search results from DuckDuckGo HTML response
-func parseHTMLSearchResults(html string) []SearchResult {
-	results := make([]SearchResult, 0)
-
-	// Find all result blocks - look for result__a class (title links)
-	// Pattern: <a rel="nofollow" class="result__a" href="...">Title</a>
-	// And snippet: <a class="result__snippet" href="...">Snippet text</a>
-
-	remaining := html
-	for i := 0; i < 10; i++ { // Limit to 10 results
-		// Find result title
-		titleStart := strings.Index(remaining, `class="result__a"`)
-		if titleStart == -1 {
-			break
-		}
-
-		remaining = remaining[titleStart:]
-
-		// Extract URL from href
-		hrefStart := strings.Index(remaining, `href="`)
-		if hrefStart == -1 {
-			break
-		}
-		hrefStart += 6
-		hrefEnd := strings.Index(remaining[hrefStart:], `"`)
-		if hrefEnd == -1 {
-			break
-		}
-		resultURL := remaining[hrefStart : hrefStart+hrefEnd]
-
-		// Decode DuckDuckGo redirect URL
-		if strings.Contains(resultURL, "uddg=") {
-			if decoded, err := url.QueryUnescape(resultURL); err == nil {
-				if idx := strings.Index(decoded, "uddg="); idx != -1 {
-					resultURL = decoded[idx+5:]
-					if ampIdx := strings.Index(resultURL, "&"); ampIdx != -1 {
-						resultURL = resultURL[:ampIdx]
-					}
-				}
-			}
-		}
-
-		// Extract title text
-		titleTagStart := strings.Index(remaining, ">")
-		if titleTagStart == -1 {
-			break
-		}
-		titleTagEnd := strings.Index(remaining[titleTagStart:], "</a>")
-		if titleTagEnd == -1 {
-			break
-		}
-		title := remaining[titleTagStart+1 : titleTagStart+titleTagEnd]
-		title = stripHTMLTags(title)
-		title = strings.TrimSpace(title)
-
-		// Move past this result
-		remaining = remaining[titleTagStart+titleTagEnd:]
-
-		// Try to find snippet
-		snippet := ""
-		snippetStart := strings.Index(remaining, `class="result__snippet"`)
-		nextResultStart := strings.Index(remaining, `class="result__a"`)
-
-		if snippetStart != -1 && (nextResultStart == -1 || snippetStart < nextResultStart) {
-			snippetRemaining := remaining[snippetStart:]
-			snippetTagStart := strings.Index(snippetRemaining, ">")
-			if snippetTagStart != -1 {
-				snippetTagEnd := strings.Index(snippetRemaining[snippetTagStart:], "</a>")
-				if snippetTagEnd != -1 {
-					snippet = snippetRemaining[snippetTagStart+1 : snippetTagStart+snippetTagEnd]
-					snippet = stripHTMLTags(snippet)
-					snippet = strings.TrimSpace(snippet)
-				}
-			}
+	var responseText string
+	var usage Usage
+	if onChunk != nil {
+		streamed, streamedUsage, err := readOllamaStream(resp.Body, onChunk)
+		if err != nil {
+			return "", Usage{}, err
 		}
-
-		if title != "" {
-			results = append(results, SearchResult{
-				Title:   title,
-				URL:     resultURL,
-				Snippet: snippet,
-			})
+		responseText = strings.TrimSpace(streamed)
+		usage = streamedUsage
+	} else {
+		var result OllamaResponse
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			return "", Usage{}, fmt.Errorf("decode response: %w", err)
 		}
-	}
-
-	return results
-}
-
-// stripHTMLTags removes HTML tags from a string
-func stripHTMLTags(s string) string {
-	var result strings.Builder
-	inTag := false
-	for _, r := range s {
-		if r == '<' {
-			inTag = true
-		} else if r == '>' {
-			inTag = false
-		} else if !inTag {
-			result.WriteRune(r)
+		if result.Error != "" {
+			return "", Usage{}, fmt.Errorf("ollama error: %s", result.Error)
 		}
+		responseText = strings.TrimSpace(result.Response)
+		usage = Usage{PromptTokens: result.PromptEvalCount, CompletionTokens: result.EvalCount}
 	}
-	// Also decode common HTML entities
-	output := result.String()
-	output = strings.ReplaceAll(output, "&amp;", "&")
-	output = strings.ReplaceAll(output, "&lt;", "<")
-	output = strings.ReplaceAll(output, "&gt;", ">")
-	output = strings.ReplaceAll(output, "&quot;", "\"")
-	output = strings.ReplaceAll(output, "&#39;", "'")
-	output = strings.ReplaceAll(output, "&nbsp;", " ")
-	return output
-}
+	log.Printf("[Ollama] Response length: %d", len(responseText))
 
-// WebSearchResponse represents the aggregated search response
-type WebSearchResponse struct {
-	Query   string         `json:"query"`
-	Results []SearchResult `json:"results"`
+	return responseText, usage, nil
 }
 
-// SearchResult represents a single search result
-type SearchResult struct {
-	Title   string `json:"title"`
-	URL     string `json:"url"`
-	Snippet string `json:"snippet"`
+// answerRules returns the formatting rules placed before a question
+func answerRules(language string) string {
+	return prompts.Render(prompts.Answer, prompts.NewData(language))
 }
 
-// FormatSearchResultsForLLM formats search results into context for LLM
-func FormatSearchResultsForLLM(response *WebSearchResponse, language string) string {
-	if response == nil || len(response.Results) == 0 {
-		if language == "zh-TW" || language == "zh" {
-			return "未找到相關搜尋結果。"
+// readOllamaStream reads the NDJSON stream returned by /api/generate with
+// stream enabled, passes each partial response to onChunk and returns the
+// full text with the usage of the final object
+func readOllamaStream(body io.Reader, onChunk func(chunk string)) (string, Usage, error) {
+	decoder := json.NewDecoder(body)
+	var full strings.Builder
+	var usage Usage
+	for {
+		var part OllamaResponse
+		if err := decoder.Decode(&part); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return full.String(), usage, fmt.Errorf("decode stream: %w", err)
 		}
-		return "No search results found."
-	}
-
-	var sb strings.Builder
-
-	for i, result := range response.Results {
-		if i >= 5 {
-			break
+		if part.Error != "" {
+			return full.String(), usage, fmt.Errorf("ollama error: %s", part.Error)
 		}
-		sb.WriteString(fmt.Sprintf("%d. %s\n", i+1, result.Title))
-		if result.Snippet != "" && result.Snippet != result.Title {
-			sb.WriteString(fmt.Sprintf("   %s\n", result.Snippet))
+		if part.Response != "" {
+			full.WriteString(part.Response)
+			onChunk(part.Response)
 		}
-		if result.URL != "" {
-			sb.WriteString(fmt.Sprintf("   來源: %s\n", result.URL))
+		if part.Done {
+			usage = Usage{PromptTokens: part.PromptEvalCount, CompletionTokens: part.EvalCount}
+			break
 		}
-		sb.WriteString("\n")
 	}
-
-	return sb.String()
+	return full.String(), usage, nil
 }
@@ -0,0 +1,216 @@
+package ocr
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultTesseractLanguages covers the Traditional Chinese and English
+// screens Korner is used on
+const DefaultTesseractLanguages = "chi_tra+eng"
+
+// TesseractEngine reads text with a local tesseract binary, no server needed
+type TesseractEngine struct {
+	Path      string // Empty searches PATH and the usual install folders
+	Languages string // Empty uses DefaultTesseractLanguages
+}
+
+func (e *TesseractEngine) Name() string { return EngineTesseract }
+
+// Available reports whether the tesseract binary can be found
+func (e *TesseractEngine) Available() bool {
+	return e.binary() != ""
+}
+
+func (e *TesseractEngine) binary() string {
+	if e.Path != "" {
+		if _, err := os.Stat(e.Path); err == nil {
+			return e.Path
+		}
+		return ""
+	}
+	return findTesseract()
+}
+
+func (e *TesseractEngine) Recognize(ctx context.Context, imageBase64 string) (*Result, error) {
+	binary := e.binary()
+	if binary == "" {
+		return nil, errors.New("tesseract not found, install it or set its path in Settings")
+	}
+	languages := e.Languages
+	if languages == "" {
+		languages = DefaultTesseractLanguages
+	}
+
+	image, err := base64.StdEncoding.DecodeString(stripDataURL(imageBase64))
+	if err != nil {
+		return nil, fmt.Errorf("decode image: %w", err)
+	}
+	file, err := os.CreateTemp("", "korner-ocr-*.png")
+	if err != nil {
+		return nil, fmt.Errorf("create temp image: %w", err)
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.Write(image); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("write temp image: %w", err)
+	}
+	file.Close()
+
+	log.Printf("[Tesseract] Reading %d bytes with languages %s", len(image), languages)
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, binary, file.Name(), "stdout", "-l", languages, "tsv")
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	hideWindow(cmd)
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("tesseract failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	result := newResult(EngineTesseract, imageBase64, parseTesseractTSV(stdout.String()))
+	log.Printf("[Tesseract] Recognized %d blocks, %d tables", len(result.Blocks), len(result.Tables))
+	return result, nil
+}
+
+// parseTesseractTSV reads the word rows of tesseract's TSV output into
+// paragraphs of lines, keeping tesseract's reading order
+func parseTesseractTSV(tsv string) []Block {
+	type lineKey struct{ page, block, par, line int }
+	type parKey struct{ page, block, par int }
+	lines := make(map[lineKey][]Word)
+	var lineOrder []lineKey
+	var parOrder []parKey
+	pars := make(map[parKey][]lineKey)
+
+	for i, row := range strings.Split(tsv, "\n") {
+		fields := strings.Split(strings.TrimRight(row, "\r"), "\t")
+		if i == 0 || len(fields) < 12 || fields[0] != "5" {
+			continue // Header and page, block, paragraph and line rows
+		}
+		text := strings.TrimSpace(fields[11])
+		if text == "" {
+			continue
+		}
+		n := make([]int, 10)
+		for j := range n {
+			n[j], _ = strconv.Atoi(fields[j+1])
+		}
+		conf, _ := strconv.ParseFloat(fields[10], 64)
+
+		key := lineKey{n[0], n[1], n[2], n[3]}
+		if _, ok := lines[key]; !ok {
+			par := parKey{n[0], n[1], n[2]}
+			if _, ok := pars[par]; !ok {
+				parOrder = append(parOrder, par)
+			}
+			pars[par] = append(pars[par], key)
+			lineOrder = append(lineOrder, key)
+		}
+		lines[key] = append(lines[key], Word{
+			Text:       text,
+			Box:        Box{X: n[5], Y: n[6], Width: n[7], Height: n[8]},
+			Confidence: conf / 100,
+		})
+	}
+
+	blocks := make([]Block, 0, len(parOrder))
+	for _, par := range parOrder {
+		parLines := make([]Line, len(pars[par]))
+		for i, key := range pars[par] {
+			parLines[i] = wordsLine(lines[key])
+		}
+		blocks = append(blocks, newBlock(parLines))
+	}
+	return blocks
+}
+
+// wordsLine builds a line from its words, sorted left to right
+func wordsLine(words []Word) Line {
+	sort.SliceStable(words, func(i, j int) bool { return words[i].Box.X < words[j].Box.X })
+
+	texts := make([]string, len(words))
+	box := words[0].Box
+	var conf float64
+	for i, w := range words {
+		texts[i] = w.Text
+		box = unionBox(box, w.Box)
+		conf += w.Confidence
+	}
+	return Line{
+		Text:       joinWords(texts),
+		Box:        &box,
+		Confidence: conf / float64(len(words)),
+		Words:      words,
+	}
+}
+
+// joinWords joins words with spaces, except between CJK characters which
+// tesseract splits into separate words
+func joinWords(words []string) string {
+	var b strings.Builder
+	for i, w := range words {
+		if i > 0 && !(endsWithCJK(words[i-1]) && startsWithCJK(w)) {
+			b.WriteByte(' ')
+		}
+		b.WriteString(w)
+	}
+	return b.String()
+}
+
+func startsWithCJK(s string) bool {
+	for _, r := range s {
+		return isCJK(r)
+	}
+	return false
+}
+
+func endsWithCJK(s string) bool {
+	runes := []rune(s)
+	return len(runes) > 0 && isCJK(runes[len(runes)-1])
+}
+
+func isCJK(r rune) bool {
+	return (r >= 0x3000 && r <= 0x9fff) || (r >= 0xac00 && r <= 0xd7af) || (r >= 0xf900 && r <= 0xfaff) || (r >= 0xff00 && r <= 0xffef)
+}
+
+// unionBox returns the smallest box holding a and b
+func unionBox(a Box, b Box) Box {
+	x0, y0 := min(a.X, b.X), min(a.Y, b.Y)
+	x1, y1 := max(a.X+a.Width, b.X+b.Width), max(a.Y+a.Height, b.Y+b.Height)
+	return Box{X: x0, Y: y0, Width: x1 - x0, Height: y1 - y0}
+}
+
+// findTesseract looks for tesseract in PATH and the default install folders
+func findTesseract() string {
+	if path, err := exec.LookPath("tesseract"); err == nil {
+		return path
+	}
+	if runtime.GOOS != "windows" {
+		return ""
+	}
+	candidates := []string{
+		filepath.Join(os.Getenv("ProgramFiles"), "Tesseract-OCR", "tesseract.exe"),
+		filepath.Join(os.Getenv("ProgramFiles(x86)"), "Tesseract-OCR", "tesseract.exe"),
+		filepath.Join(os.Getenv("LOCALAPPDATA"), "Programs", "Tesseract-OCR", "tesseract.exe"),
+	}
+	for _, path := range candidates {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
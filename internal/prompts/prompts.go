@@ -0,0 +1,292 @@
+package prompts
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// Names of the built-in templates. A file with the same name and a .tmpl
+// extension in the prompts directory replaces the built-in one.
+const (
+	System         = "system"          // System prompt of the chat providers
+	Answer         = "answer"          // Rules placed before an Ollama question
+	OCR            = "ocr"             // Text extraction from a screenshot
+	WebSearch      = "web_search"      // Answer built from search results
+	MeetingSummary = "meeting_summary" // Summary of a meeting transcription
+	Condense       = "condense"        // Summary of one chunk of a long input
+	MeetingNotes   = "meeting_notes"   // Meeting summary as JSON, see meeting.Notes
+	JSON           = "json"            // Asks for a JSON answer matching a schema
+	Table          = "table"           // Table in a screenshot as JSON, see table.Table
+	SearchQueries  = "search_queries"  // Web search queries planned from a question, as JSON
+)
+
+// actionsFile holds the user's quick actions in the prompts directory
+const actionsFile = "actions.json"
+
+//go:embed templates
+var builtinFS embed.FS
+
+// builtins are the templates shipped with Korner, used when the user has
+// not replaced them
+var builtins = parseBuiltins()
+
+// Data is what a template can refer to, e.g. {{.Language}} or {{.OCRText}}
+type Data struct {
+	Language      string // "zh-TW" or "en"
+	Now           string // Local time as "2006-01-02 15:04"
+	OCRText       string // Text read from the screenshot
+	Selection     string // Text the user selected or copied
+	Query         string // The user's question
+	SearchResults string // Formatted web search results
+	Transcription string // Meeting transcription
+	Text          string // Text to work on, such as a chunk to condense
+	Schema        string // JSON Schema the answer must match
+	Problem       string // Why the previous answer was rejected
+}
+
+// NewData returns template data for language with the current time filled in
+func NewData(language string) Data {
+	return Data{
+		Language: language,
+		Now:      time.Now().Format("2006-01-02 15:04"),
+	}
+}
+
+// Chinese reports whether the answer should be in Traditional Chinese, use
+// it in templates as {{if .Chinese}}
+func (d Data) Chinese() bool {
+	return d.Language == "zh-TW" || d.Language == "zh"
+}
+
+// Action is a named prompt the user can run on the current screenshot from
+// the pie menu
+type Action struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Icon   string `json:"icon,omitempty"`
+	Prompt string `json:"prompt"` // text/template rendered with Data
+	// Clipboard fills {{.Selection}} with the clipboard text. It is off
+	// unless the user turns it on, since the clipboard may hold passwords.
+	Clipboard bool `json:"clipboard,omitempty"`
+}
+
+// UsesOCR reports whether the prompt needs the screenshot as text
+func (a Action) UsesOCR() bool {
+	return strings.Contains(a.Prompt, ".OCRText")
+}
+
+// UsesSelection reports whether the action sends the clipboard text: the
+// user turned it on and the prompt refers to {{.Selection}}
+func (a Action) UsesSelection() bool {
+	return a.Clipboard && strings.Contains(a.Prompt, ".Selection")
+}
+
+// Render fills in the action's prompt
+func (a Action) Render(data Data) (string, error) {
+	t, err := template.New(a.ID).Parse(a.Prompt)
+	if err != nil {
+		return "", fmt.Errorf("parse action %s: %w", a.ID, err)
+	}
+	return execute(t, data)
+}
+
+var (
+	mu        sync.RWMutex
+	dir       string
+	overrides = map[string]*template.Template{}
+	actions   = defaultActions()
+)
+
+// DefaultDir returns ~/.korner/prompts
+func DefaultDir() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		log.Printf("[Prompts] Failed to get home directory: %v", err)
+		return "prompts"
+	}
+	return filepath.Join(homeDir, ".korner", "prompts")
+}
+
+// Dir returns the directory the templates were last loaded from
+func Dir() string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return dir
+}
+
+// Load reads template overrides and actions from promptsDir, creating it
+// with the default actions on first use. Invalid files are skipped and
+// reported in the returned error, everything else stays usable.
+func Load(promptsDir string) error {
+	if err := os.MkdirAll(promptsDir, 0755); err != nil {
+		return fmt.Errorf("create prompts directory: %w", err)
+	}
+
+	entries, err := os.ReadDir(promptsDir)
+	if err != nil {
+		return fmt.Errorf("read prompts directory: %w", err)
+	}
+
+	var problems []string
+	loaded := make(map[string]*template.Template)
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".tmpl" {
+			continue
+		}
+		name := strings.TrimSuffix(entry.Name(), ".tmpl")
+		data, err := os.ReadFile(filepath.Join(promptsDir, entry.Name()))
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", entry.Name(), err))
+			continue
+		}
+		t, err := template.New(name).Parse(string(data))
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", entry.Name(), err))
+			continue
+		}
+		loaded[name] = t
+	}
+
+	userActions, err := loadActions(promptsDir)
+	if err != nil {
+		problems = append(problems, fmt.Sprintf("%s: %v", actionsFile, err))
+		userActions = defaultActions()
+	}
+
+	mu.Lock()
+	dir = promptsDir
+	overrides = loaded
+	actions = userActions
+	mu.Unlock()
+
+	log.Printf("[Prompts] Loaded %d template overrides and %d actions from %s", len(loaded), len(userActions), promptsDir)
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid prompt files:\n%s", strings.Join(problems, "\n"))
+	}
+	return nil
+}
+
+// Render fills in the named template. A user template that fails to
+// execute falls back to the built-in one so a typo never blocks a query.
+func Render(name string, data Data) string {
+	mu.RLock()
+	t := overrides[name]
+	mu.RUnlock()
+
+	if t != nil {
+		out, err := execute(t, data)
+		if err == nil {
+			return out
+		}
+		log.Printf("[Prompts] Template %s failed, using built-in: %v", name, err)
+	}
+
+	t, ok := builtins[name]
+	if !ok {
+		log.Printf("[Prompts] Unknown template: %s", name)
+		return ""
+	}
+	out, err := execute(t, data)
+	if err != nil {
+		log.Printf("[Prompts] Built-in template %s failed: %v", name, err)
+	}
+	return out
+}
+
+// Actions returns the quick actions in the order the user defined them
+func Actions() []Action {
+	mu.RLock()
+	defer mu.RUnlock()
+	return append([]Action(nil), actions...)
+}
+
+// FindAction returns the action with the given ID
+func FindAction(id string) (Action, bool) {
+	for _, action := range Actions() {
+		if action.ID == id {
+			return action, true
+		}
+	}
+	return Action{}, false
+}
+
+// loadActions reads actions.json, writing the defaults there if it is missing
+func loadActions(promptsDir string) ([]Action, error) {
+	path := filepath.Join(promptsDir, actionsFile)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		data, _ = builtinFS.ReadFile("templates/" + actionsFile)
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			log.Printf("[Prompts] Warning: failed to write default actions: %v", err)
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
+	var list []Action
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("parse: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	valid := list[:0]
+	for _, action := range list {
+		if action.ID == "" || action.Prompt == "" {
+			return nil, fmt.Errorf("every action needs an id and a prompt")
+		}
+		if seen[action.ID] {
+			return nil, fmt.Errorf("duplicate action id: %s", action.ID)
+		}
+		if _, err := template.New(action.ID).Parse(action.Prompt); err != nil {
+			return nil, fmt.Errorf("action %s: %w", action.ID, err)
+		}
+		if action.Name == "" {
+			action.Name = action.ID
+		}
+		seen[action.ID] = true
+		valid = append(valid, action)
+	}
+	return valid, nil
+}
+
+// defaultActions returns the actions shipped with Korner
+func defaultActions() []Action {
+	data, err := builtinFS.ReadFile("templates/" + actionsFile)
+	if err != nil {
+		return nil
+	}
+	var list []Action
+	if err := json.Unmarshal(data, &list); err != nil {
+		log.Printf("[Prompts] Built-in actions are invalid: %v", err)
+	}
+	return list
+}
+
+func parseBuiltins() map[string]*template.Template {
+	out := make(map[string]*template.Template)
+	for _, name := range []string{System, Answer, OCR, WebSearch, MeetingSummary, Condense, MeetingNotes, JSON, Table, SearchQueries} {
+		data, err := builtinFS.ReadFile("templates/" + name + ".tmpl")
+		if err != nil {
+			panic(fmt.Sprintf("prompts: missing built-in template %s", name))
+		}
+		out[name] = template.Must(template.New(name).Parse(string(data)))
+	}
+	return out
+}
+
+func execute(t *template.Template, data Data) (string, error) {
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
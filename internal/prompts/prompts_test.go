@@ -0,0 +1,90 @@
+package prompts
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRenderBuiltins(t *testing.T) {
+	for _, name := range []string{System, Answer, OCR, WebSearch, MeetingSummary, Condense, MeetingNotes, JSON, Table, SearchQueries} {
+		for _, language := range []string{"zh-TW", "en"} {
+			if out := Render(name, NewData(language)); strings.TrimSpace(out) == "" {
+				t.Errorf("Render(%s, %s) is empty", name, language)
+			}
+		}
+	}
+
+	data := NewData("en")
+	data.Transcription = "we agreed to ship on Friday"
+	if out := Render(MeetingSummary, data); !strings.Contains(out, data.Transcription) {
+		t.Errorf("meeting summary does not include the transcription")
+	}
+}
+
+func TestLoadOverridesAndActions(t *testing.T) {
+	dir := t.TempDir()
+	defer Load(t.TempDir())
+
+	if err := os.WriteFile(filepath.Join(dir, "system.tmpl"), []byte("Answer in {{.Language}} at {{.Now}}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "ocr.tmpl"), []byte("{{.Missing}}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Load(dir); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	data := NewData("en")
+	data.Now = "2024-01-02 03:04"
+	if got := Render(System, data); got != "Answer in en at 2024-01-02 03:04" {
+		t.Errorf("override not used: %q", got)
+	}
+	// A template that fails to execute falls back to the built-in one
+	if got := Render(OCR, data); !strings.Contains(got, "提取") {
+		t.Errorf("broken override did not fall back: %q", got)
+	}
+
+	// The default actions are written on first load
+	if _, err := os.Stat(filepath.Join(dir, actionsFile)); err != nil {
+		t.Errorf("default actions not written: %v", err)
+	}
+	action, ok := FindAction("translate-en")
+	if !ok {
+		t.Fatalf("default action missing, got %+v", Actions())
+	}
+	if !action.UsesOCR() {
+		t.Errorf("translate action should use OCR text")
+	}
+	// The clipboard is only read for actions that opt in
+	if explain, _ := FindAction("explain-code"); explain.UsesSelection() {
+		t.Errorf("default action should not read the clipboard")
+	}
+	if !(Action{Prompt: "{{.Selection}}", Clipboard: true}).UsesSelection() {
+		t.Errorf("opted in action should read the clipboard")
+	}
+	data.OCRText = "你好"
+	out, err := action.Render(data)
+	if err != nil || !strings.HasSuffix(out, "你好") {
+		t.Errorf("Render() = %q, %v", out, err)
+	}
+}
+
+func TestLoadInvalidActions(t *testing.T) {
+	dir := t.TempDir()
+	defer Load(t.TempDir())
+
+	actionsJSON := `[{"id": "a", "prompt": "x"}, {"id": "a", "prompt": "y"}]`
+	if err := os.WriteFile(filepath.Join(dir, actionsFile), []byte(actionsJSON), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Load(dir); err == nil {
+		t.Fatal("expected an error for duplicate action IDs")
+	}
+	// The defaults stay available
+	if _, ok := FindAction("explain-code"); !ok {
+		t.Errorf("default actions not restored, got %+v", Actions())
+	}
+}
@@ -0,0 +1,155 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// SearXNGProvider queries a SearXNG instance. The instance must allow the
+// json format under search.formats in its settings.yml.
+type SearXNGProvider struct {
+	URL string // Base URL of the instance, such as http://localhost:8888
+}
+
+// searxngResponse is the part of SearXNG's JSON answer Korner reads
+type searxngResponse struct {
+	Results []struct {
+		Title   string `json:"title"`
+		URL     string `json:"url"`
+		Content string `json:"content"`
+	} `json:"results"`
+}
+
+func (p *SearXNGProvider) Name() string { return SearXNG }
+
+func (p *SearXNGProvider) Search(ctx context.Context, query string, limit int) ([]Result, error) {
+	limit = clampLimit(limit)
+	endpoint, err := withQuery(strings.TrimSuffix(p.URL, "/")+"/search", url.Values{
+		"q":      {query},
+		"format": {"json"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+
+	var decoded searxngResponse
+	if err := getJSON(req, &decoded); err != nil {
+		return nil, fmt.Errorf("SearXNG: %w", err)
+	}
+	results := make([]Result, 0, len(decoded.Results))
+	for _, r := range decoded.Results {
+		results = append(results, Result{Title: r.Title, URL: r.URL, Snippet: r.Content})
+	}
+	log.Printf("[Search] SearXNG found %d results", len(results))
+	return truncate(results, limit), nil
+}
+
+// DefaultBraveURL is the Brave Search API web search endpoint
+const DefaultBraveURL = "https://api.search.brave.com/res/v1/web/search"
+
+// BraveProvider queries the Brave Search API
+type BraveProvider struct {
+	URL    string // Empty uses DefaultBraveURL
+	APIKey string
+}
+
+// braveResponse is the part of Brave's JSON answer Korner reads
+type braveResponse struct {
+	Web struct {
+		Results []struct {
+			Title       string `json:"title"`
+			URL         string `json:"url"`
+			Description string `json:"description"`
+		} `json:"results"`
+	} `json:"web"`
+}
+
+func (p *BraveProvider) Name() string { return Brave }
+
+func (p *BraveProvider) Search(ctx context.Context, query string, limit int) ([]Result, error) {
+	limit = clampLimit(limit)
+	base := p.URL
+	if base == "" {
+		base = DefaultBraveURL
+	}
+	// Brave returns at most 20 results per request
+	endpoint, err := withQuery(base, url.Values{"q": {query}, "count": {strconv.Itoa(min(limit, 20))}})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("X-Subscription-Token", p.APIKey)
+
+	var decoded braveResponse
+	if err := getJSON(req, &decoded); err != nil {
+		return nil, fmt.Errorf("Brave Search: %w", err)
+	}
+	results := make([]Result, 0, len(decoded.Web.Results))
+	for _, r := range decoded.Web.Results {
+		results = append(results, Result{Title: stripHTMLTags(r.Title), URL: r.URL, Snippet: stripHTMLTags(r.Description)})
+	}
+	log.Printf("[Search] Brave found %d results", len(results))
+	return truncate(results, limit), nil
+}
+
+// DefaultBingURL is the Bing Web Search API endpoint
+const DefaultBingURL = "https://api.bing.microsoft.com/v7.0/search"
+
+// BingProvider queries the Bing Web Search API
+type BingProvider struct {
+	URL    string // Empty uses DefaultBingURL
+	APIKey string
+}
+
+// bingResponse is the part of Bing's JSON answer Korner reads
+type bingResponse struct {
+	WebPages struct {
+		Value []struct {
+			Name    string `json:"name"`
+			URL     string `json:"url"`
+			Snippet string `json:"snippet"`
+		} `json:"value"`
+	} `json:"webPages"`
+}
+
+func (p *BingProvider) Name() string { return Bing }
+
+func (p *BingProvider) Search(ctx context.Context, query string, limit int) ([]Result, error) {
+	limit = clampLimit(limit)
+	base := p.URL
+	if base == "" {
+		base = DefaultBingURL
+	}
+	endpoint, err := withQuery(base, url.Values{"q": {query}, "count": {strconv.Itoa(min(limit, 50))}})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Ocp-Apim-Subscription-Key", p.APIKey)
+
+	var decoded bingResponse
+	if err := getJSON(req, &decoded); err != nil {
+		return nil, fmt.Errorf("Bing Search: %w", err)
+	}
+	results := make([]Result, 0, len(decoded.WebPages.Value))
+	for _, r := range decoded.WebPages.Value {
+		results = append(results, Result{Title: r.Name, URL: r.URL, Snippet: r.Snippet})
+	}
+	log.Printf("[Search] Bing found %d results", len(results))
+	return truncate(results, limit), nil
+}
@@ -0,0 +1,71 @@
+package search
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MaxSources is how many results are numbered for the model to cite
+const MaxSources = 5
+
+// Source is a result the model can cite as [ID]
+type Source struct {
+	ID    int    `json:"id"`
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+// Sources numbers the results Format lists, starting at 1
+func Sources(results []Result) []Source {
+	results = truncate(results, MaxSources)
+	sources := make([]Source, len(results))
+	for i, result := range results {
+		sources[i] = Source{ID: i + 1, Title: result.Title, URL: result.URL}
+	}
+	return sources
+}
+
+// citationPattern matches [1], [1, 2] and the full width 【1】 some models use
+var citationPattern = regexp.MustCompile(`[\[【]\s*\d+(?:\s*[,，、]\s*\d+)*\s*[\]】]`)
+
+// Cite normalizes the citation markers in answer to [n] and returns the
+// sources it cites, in ID order. Brackets holding any number that is not
+// a source ID, such as arr[10] or [2024], are left as they are. When the
+// answer cites nothing, all sources are returned, since the answer was
+// still written from them.
+func Cite(answer string, sources []Source) (string, []Source) {
+	byID := make(map[int]Source, len(sources))
+	for _, source := range sources {
+		byID[source.ID] = source
+	}
+
+	cited := make(map[int]bool)
+	answer = citationPattern.ReplaceAllStringFunc(answer, func(marker string) string {
+		var ids []int
+		for _, field := range strings.FieldsFunc(marker, func(r rune) bool { return r < '0' || r > '9' }) {
+			id, err := strconv.Atoi(field)
+			if _, ok := byID[id]; err != nil || !ok {
+				return marker
+			}
+			ids = append(ids, id)
+		}
+		var sb strings.Builder
+		for _, id := range ids {
+			cited[id] = true
+			sb.WriteString("[" + strconv.Itoa(id) + "]")
+		}
+		return sb.String()
+	})
+
+	if len(cited) == 0 {
+		return answer, sources
+	}
+	list := make([]Source, 0, len(cited))
+	for id := range cited {
+		list = append(list, byID[id])
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return answer, list
+}
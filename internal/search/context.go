@@ -0,0 +1,76 @@
+package search
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Kelen/Korner/internal/tokens"
+)
+
+// chunkTokens is the size of the page excerpts BuildContext hands out
+const chunkTokens = 400
+
+// BuildContext lists results like Format, then adds the text of the fetched
+// pages until the whole context takes maxTokens. The pages take turns one
+// chunk at a time, so every page that could be read gets its opening
+// paragraphs in before any page gets more.
+func BuildContext(results []Result, pages []Page, maxTokens int, language string) string {
+	listing := Format(results, language)
+	if len(pages) > MaxSources {
+		pages = pages[:MaxSources] // Only listed results can be cited
+	}
+	remaining := maxTokens - tokens.Estimate(listing)
+
+	chunks := make([][]string, len(pages))
+	for i, page := range pages {
+		if page.Err == nil {
+			chunks[i] = tokens.Split(page.Text, chunkTokens)
+		}
+	}
+
+	// Hand out chunks round robin while they fit
+	taken := make([]int, len(pages))
+	for i, page := range pages {
+		if len(chunks[i]) > 0 {
+			remaining -= tokens.Estimate(pageHeader(i, page))
+		}
+	}
+	for more := true; more && remaining > 0; {
+		more = false
+		for i := range pages {
+			if taken[i] >= len(chunks[i]) {
+				continue
+			}
+			cost := tokens.Estimate(chunks[i][taken[i]])
+			if cost > remaining {
+				continue
+			}
+			remaining -= cost
+			taken[i]++
+			more = true
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(listing)
+	for i, page := range pages {
+		if taken[i] == 0 {
+			continue
+		}
+		sb.WriteString(pageHeader(i, page))
+		for _, chunk := range chunks[i][:taken[i]] {
+			sb.WriteString(strings.TrimSpace(chunk))
+			sb.WriteString("\n\n")
+		}
+	}
+	return strings.TrimSpace(sb.String()) + "\n"
+}
+
+// pageHeader introduces the text of the i-th page under its source ID
+func pageHeader(i int, page Page) string {
+	title := page.Title
+	if title == "" {
+		title = page.URL
+	}
+	return fmt.Sprintf("--- [%d] %s (%s) ---\n", i+1, title, page.URL)
+}
@@ -0,0 +1,158 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultDuckDuckGoURL is DuckDuckGo's HTML-only search page
+const DefaultDuckDuckGoURL = "https://html.duckduckgo.com/html/"
+
+// DuckDuckGoProvider scrapes DuckDuckGo's HTML search page. It needs no
+// key but breaks when DuckDuckGo changes its markup, prefer an API backed
+// provider when one is available.
+type DuckDuckGoProvider struct {
+	URL string // Empty uses DefaultDuckDuckGoURL
+}
+
+func (p *DuckDuckGoProvider) Name() string { return DuckDuckGo }
+
+func (p *DuckDuckGoProvider) Search(ctx context.Context, query string, limit int) ([]Result, error) {
+	limit = clampLimit(limit)
+	base := p.URL
+	if base == "" {
+		base = DefaultDuckDuckGoURL
+	}
+	endpoint, err := withQuery(base, url.Values{"q": {query}})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+	req.Header.Set("Accept-Language", "zh-TW,zh;q=0.9,en;q=0.8")
+
+	resp, err := newClient(30 * time.Second).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("http request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("DuckDuckGo returned status %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+
+	results, err := parseDuckDuckGo(string(body), limit)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[Search] DuckDuckGo found %d results", len(results))
+	return results, nil
+}
+
+// errUnknownPage means the page has neither results nor DuckDuckGo's "no
+// results" notice, usually a captcha or changed markup
+var errUnknownPage = errors.New("DuckDuckGo page not recognized, its markup may have changed or the request was blocked")
+
+// parseDuckDuckGo extracts results from DuckDuckGo's HTML page
+func parseDuckDuckGo(page string, limit int) ([]Result, error) {
+	results := make([]Result, 0)
+
+	// Title links:  <a rel="nofollow" class="result__a" href="...">Title</a>
+	// Snippets:     <a class="result__snippet" href="...">Snippet text</a>
+	remaining := page
+	for len(results) < limit {
+		titleStart := strings.Index(remaining, `class="result__a"`)
+		if titleStart == -1 {
+			break
+		}
+		remaining = remaining[titleStart:]
+
+		hrefStart := strings.Index(remaining, `href="`)
+		if hrefStart == -1 {
+			break
+		}
+		hrefStart += 6
+		hrefEnd := strings.Index(remaining[hrefStart:], `"`)
+		if hrefEnd == -1 {
+			break
+		}
+		resultURL := resultLink(html.UnescapeString(remaining[hrefStart : hrefStart+hrefEnd]))
+
+		titleTagStart := strings.Index(remaining, ">")
+		if titleTagStart == -1 {
+			break
+		}
+		titleTagEnd := strings.Index(remaining[titleTagStart:], "</a>")
+		if titleTagEnd == -1 {
+			break
+		}
+		title := strings.TrimSpace(stripHTMLTags(remaining[titleTagStart+1 : titleTagStart+titleTagEnd]))
+		remaining = remaining[titleTagStart+titleTagEnd:]
+
+		// The snippet belongs to this result only if it comes before the next title
+		snippet := ""
+		snippetStart := strings.Index(remaining, `class="result__snippet"`)
+		nextResultStart := strings.Index(remaining, `class="result__a"`)
+		if snippetStart != -1 && (nextResultStart == -1 || snippetStart < nextResultStart) {
+			snippetRemaining := remaining[snippetStart:]
+			if tagStart := strings.Index(snippetRemaining, ">"); tagStart != -1 {
+				if tagEnd := strings.Index(snippetRemaining[tagStart:], "</a>"); tagEnd != -1 {
+					snippet = strings.TrimSpace(stripHTMLTags(snippetRemaining[tagStart+1 : tagStart+tagEnd]))
+				}
+			}
+		}
+
+		if title != "" {
+			results = append(results, Result{Title: title, URL: resultURL, Snippet: snippet})
+		}
+	}
+
+	if len(results) == 0 && !strings.Contains(page, `class="no-results"`) {
+		return nil, errUnknownPage
+	}
+	return results, nil
+}
+
+// resultLink returns the target of DuckDuckGo's //duckduckgo.com/l/?uddg=
+// redirect links, or link itself
+func resultLink(link string) string {
+	u, err := url.Parse(link)
+	if err != nil {
+		return link
+	}
+	if target := u.Query().Get("uddg"); target != "" {
+		return target
+	}
+	return link
+}
+
+// stripHTMLTags removes HTML tags and decodes entities
+func stripHTMLTags(s string) string {
+	var result strings.Builder
+	inTag := false
+	for _, r := range s {
+		if r == '<' {
+			inTag = true
+		} else if r == '>' {
+			inTag = false
+		} else if !inTag {
+			result.WriteRune(r)
+		}
+	}
+	return html.UnescapeString(result.String())
+}
@@ -0,0 +1,133 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Defaults of FetchOptions
+const (
+	DefaultFetchPages   = 3
+	DefaultFetchBytes   = 1 << 20
+	DefaultFetchTimeout = 10 * time.Second
+)
+
+// FetchOptions limits how much FetchPages downloads
+type FetchOptions struct {
+	Pages    int           // How many of the top results to read, 0 uses DefaultFetchPages
+	MaxBytes int64         // Larger pages are cut, 0 uses DefaultFetchBytes
+	Timeout  time.Duration // Per page, 0 uses DefaultFetchTimeout
+}
+
+// Page is the readable text of a search result
+type Page struct {
+	URL   string
+	Title string
+	Text  string // Empty when the page could not be read
+	Err   error
+}
+
+// FetchPages downloads the top results at the same time and extracts their
+// main text. Pages come back in the order of results; a page that fails
+// carries its error and does not hold up the others.
+func FetchPages(ctx context.Context, results []Result, opts FetchOptions) []Page {
+	if opts.Pages <= 0 {
+		opts.Pages = DefaultFetchPages
+	}
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = DefaultFetchBytes
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultFetchTimeout
+	}
+	results = truncate(results, opts.Pages)
+
+	client := newClient(opts.Timeout)
+	pages := make([]Page, len(results))
+	var wg sync.WaitGroup
+	for i, result := range results {
+		wg.Add(1)
+		go func(i int, result Result) {
+			defer wg.Done()
+			start := time.Now()
+			page := fetchPage(ctx, client, result.URL, opts.MaxBytes)
+			if page.Err != nil {
+				log.Printf("[Search] Skipping %s: %v", result.URL, page.Err)
+			} else {
+				log.Printf("[Search] Read %s (%d bytes of text in %v)", result.URL, len(page.Text), time.Since(start).Round(time.Millisecond))
+			}
+			pages[i] = page
+		}(i, result)
+	}
+	wg.Wait()
+	return pages
+}
+
+// fetchPage downloads one page and extracts its text
+func fetchPage(ctx context.Context, client *http.Client, url string, maxBytes int64) Page {
+	page := Page{URL: url}
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		page.Err = errors.New("not a web page")
+		return page
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		page.Err = fmt.Errorf("create request: %w", err)
+		return page
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,text/plain")
+	req.Header.Set("Accept-Language", "zh-TW,zh;q=0.9,en;q=0.8")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		page.Err = fmt.Errorf("http request: %w", err)
+		return page
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		page.Err = fmt.Errorf("status %d", resp.StatusCode)
+		return page
+	}
+
+	mediaType, params, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "" && mediaType != "text/html" && mediaType != "application/xhtml+xml" && mediaType != "text/plain" {
+		page.Err = fmt.Errorf("unsupported content type %s", mediaType)
+		return page
+	}
+	if charset := strings.ToLower(params["charset"]); charset != "" && charset != "utf-8" && charset != "utf8" {
+		page.Err = fmt.Errorf("unsupported charset %s", charset)
+		return page
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBytes))
+	if err != nil {
+		page.Err = fmt.Errorf("read response: %w", err)
+		return page
+	}
+	// A cut may end inside a character, only the rest has to be valid
+	text := strings.ToValidUTF8(string(body), "")
+	if !utf8.ValidString(string(body)) && len(text) < len(body)*9/10 {
+		page.Err = errors.New("page is not UTF-8")
+		return page
+	}
+
+	if mediaType == "text/plain" {
+		page.Text = strings.TrimSpace(text)
+	} else {
+		page.Title, page.Text = ExtractText(text)
+	}
+	if page.Text == "" {
+		page.Err = errors.New("no readable text")
+	}
+	return page
+}
@@ -0,0 +1,136 @@
+package search
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Kelen/Korner/internal/tokens"
+)
+
+const articlePage = `<!DOCTYPE html>
+<html><head><title>Go 1.24 &amp; you</title>
+<script>var x = "<p>not text</p>";</script><style>p { color: red }</style></head>
+<body>
+<header><a href="/">Home</a> <a href="/blog">Blog</a></header>
+<nav><ul><li><a href="/a">Archive</a></li><li><a href="/b">About</a></li></ul></nav>
+<div class="ad">Subscribe now</div>
+<article>
+<h1>Go 1.24 is released</h1>
+<p class="byline">By the Go team</p>
+<p>Go 1.24 brings generic type aliases, a faster map implementation and a new <a href="/weak">weak</a> package.</p>
+<p>The release is available for download now &mdash; see the release notes for details.</p>
+<ul class="tags"><li><a href="/t/go">go</a></li><li><a href="/t/release">release</a></li></ul>
+</article>
+<footer>Copyright 2025 and a very long footer line that would pass the length check.</footer>
+</body></html>`
+
+func TestExtractText(t *testing.T) {
+	title, text := ExtractText(articlePage)
+	if title != "Go 1.24 & you" {
+		t.Errorf("title = %q", title)
+	}
+	want := "Go 1.24 is released\n\n" +
+		"Go 1.24 brings generic type aliases, a faster map implementation and a new weak package.\n\n" +
+		"The release is available for download now — see the release notes for details."
+	if text != want {
+		t.Errorf("text = %q, want %q", text, want)
+	}
+}
+
+func TestExtractTextWithoutArticle(t *testing.T) {
+	_, text := ExtractText(`<body><nav><a href="/">首頁</a></nav>
+<div><p>台北今天多雲時晴，午後山區有局部短暫雷陣雨。</p><p>短</p></div>
+<div><a href="/1">相關新聞一則相關新聞一則相關新聞一則</a> <a href="/2">相關新聞二則相關新聞二則</a></div></body>`)
+	if text != "台北今天多雲時晴，午後山區有局部短暫雷陣雨。" {
+		t.Errorf("text = %q", text)
+	}
+}
+
+func TestExtractTextWebForms(t *testing.T) {
+	_, text := ExtractText(`<body><form method="post" action="./news.aspx" id="form1">
+<input type="hidden" name="__VIEWSTATE" value="abc" />
+<div id="content"><p>本府今日公布明年度預算案，總額較今年增加百分之三。</p></div>
+<textarea name="comment">請輸入意見請輸入意見請輸入意見請輸入意見請輸入意見</textarea>
+<button type="submit">送出送出送出送出送出送出送出送出送出送出送出送出送出</button>
+</form></body>`)
+	if text != "本府今日公布明年度預算案，總額較今年增加百分之三。" {
+		t.Errorf("text = %q", text)
+	}
+}
+
+func TestFetchPages(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/article", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(articlePage))
+	})
+	mux.HandleFunc("/report.pdf", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Write([]byte("%PDF-1.7"))
+	})
+	mux.HandleFunc("/huge", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(strings.Repeat("長", 10000)))
+	})
+	mux.HandleFunc("/missing", http.NotFound)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	results := []Result{
+		{Title: "Article", URL: server.URL + "/article"},
+		{Title: "PDF", URL: server.URL + "/report.pdf"},
+		{Title: "Huge", URL: server.URL + "/huge"},
+		{Title: "Missing", URL: server.URL + "/missing"},
+		{Title: "Not fetched", URL: server.URL + "/article"},
+	}
+	pages := FetchPages(context.Background(), results, FetchOptions{Pages: 4, MaxBytes: 1000})
+	if len(pages) != 4 {
+		t.Fatalf("got %d pages, want the top 4", len(pages))
+	}
+	if pages[0].Err != nil || pages[0].Title != "Go 1.24 & you" || !strings.Contains(pages[0].Text, "weak package") {
+		t.Errorf("article = %+v", pages[0])
+	}
+	if pages[1].Err == nil || pages[1].Text != "" {
+		t.Errorf("pdf = %+v, want it skipped", pages[1])
+	}
+	// 1000 bytes cut the 3 byte characters after 333 of them
+	if pages[2].Err != nil || pages[2].Text != strings.Repeat("長", 333) {
+		t.Errorf("huge page has %d bytes, err %v", len(pages[2].Text), pages[2].Err)
+	}
+	if pages[3].Err == nil || !strings.Contains(pages[3].Err.Error(), "404") {
+		t.Errorf("missing = %+v", pages[3])
+	}
+}
+
+func TestBuildContext(t *testing.T) {
+	results := []Result{
+		{Title: "One", URL: "https://one.example", Snippet: "first"},
+		{Title: "Two", URL: "https://two.example", Snippet: "second"},
+	}
+	long := strings.Repeat("這是一段很長的內容。\n", 300)
+	pages := []Page{
+		{URL: "https://one.example", Title: "One", Text: long},
+		{URL: "https://two.example", Title: "Two", Text: long},
+	}
+
+	built := BuildContext(results, pages, 1500, "zh-TW")
+	if got := tokens.Estimate(built); got > 1500 {
+		t.Errorf("context takes %d tokens, budget is 1500", got)
+	}
+	if !strings.HasPrefix(built, Format(results, "zh-TW")) {
+		t.Errorf("context should start with the result list:\n%s", built)
+	}
+	for _, header := range []string{"--- [1] One (https://one.example) ---", "--- [2] Two (https://two.example) ---"} {
+		if !strings.Contains(built, header) {
+			t.Errorf("context misses %q, both pages should get a share", header)
+		}
+	}
+
+	// Without room for page text only the list remains
+	if got := BuildContext(results, pages, 10, "zh-TW"); strings.Contains(got, "---") {
+		t.Errorf("tiny budget still added page text:\n%s", got)
+	}
+}
@@ -0,0 +1,97 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// rrfK damps the weight of the top ranks in reciprocal rank fusion. 60 is
+// the value from the original paper and what most search engines use.
+const rrfK = 60
+
+// SearchAll runs every query at the same time and merges the results with
+// Merge. It fails only when every query fails.
+func SearchAll(ctx context.Context, p Provider, queries []string, limit int) ([]Result, error) {
+	limit = clampLimit(limit)
+	lists := make([][]Result, len(queries))
+	errs := make([]error, len(queries))
+	var wg sync.WaitGroup
+	for i, query := range queries {
+		wg.Add(1)
+		go func(i int, query string) {
+			defer wg.Done()
+			lists[i], errs[i] = p.Search(ctx, query, limit)
+			if errs[i] != nil {
+				log.Printf("[Search] Query %q failed: %v", query, errs[i])
+			}
+		}(i, query)
+	}
+	wg.Wait()
+
+	failed := 0
+	for _, err := range errs {
+		if err != nil {
+			failed++
+		}
+	}
+	if len(queries) > 0 && failed == len(queries) {
+		return nil, errors.Join(errs...)
+	}
+	return Merge(lists, limit), nil
+}
+
+// Merge combines result lists of several queries into one, best first.
+// Pages found by more than one query or ranked higher by one score higher,
+// following reciprocal rank fusion. A page is listed once, with the title
+// and the longest snippet any query returned.
+func Merge(lists [][]Result, limit int) []Result {
+	type scored struct {
+		result Result
+		score  float64
+		first  int // Order of discovery, breaks ties
+	}
+	byURL := make(map[string]*scored)
+	var merged []*scored
+	for _, list := range lists {
+		for rank, result := range list {
+			key := urlKey(result.URL)
+			entry, ok := byURL[key]
+			if !ok {
+				entry = &scored{result: result, first: len(merged)}
+				byURL[key] = entry
+				merged = append(merged, entry)
+			} else if len(result.Snippet) > len(entry.result.Snippet) {
+				entry.result.Snippet = result.Snippet
+			}
+			entry.score += 1 / float64(rrfK+rank+1)
+		}
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		if merged[i].score != merged[j].score {
+			return merged[i].score > merged[j].score
+		}
+		return merged[i].first < merged[j].first
+	})
+	results := make([]Result, 0, min(len(merged), limit))
+	for _, entry := range merged[:min(len(merged), limit)] {
+		results = append(results, entry.result)
+	}
+	return results
+}
+
+// urlKey identifies a page regardless of scheme, "www.", letter case of the
+// host, a trailing slash or a fragment
+func urlKey(raw string) string {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Host == "" {
+		return raw
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Host), "www.")
+	return host + strings.TrimSuffix(u.EscapedPath(), "/") + "?" + u.RawQuery
+}
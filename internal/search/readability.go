@@ -0,0 +1,219 @@
+package search
+
+import (
+	"html"
+	"strings"
+	"unicode/utf8"
+)
+
+// Tags whose content is never page text
+var skipTags = map[string]bool{
+	"script": true, "style": true, "noscript": true, "svg": true,
+	"template": true, "iframe": true, "canvas": true, "select": true, "textarea": true,
+}
+
+// Tags that hold site chrome rather than the article. form is not one of
+// them, ASP.NET WebForms pages wrap the whole body in it.
+var boilerplateTags = map[string]bool{
+	"nav": true, "header": true, "footer": true, "aside": true,
+	"menu": true, "button": true, "dialog": true,
+}
+
+// Tags that start a new paragraph of text
+var blockTags = map[string]bool{
+	"p": true, "div": true, "br": true, "li": true, "tr": true, "td": true, "th": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"article": true, "section": true, "main": true, "pre": true, "blockquote": true,
+	"ul": true, "ol": true, "table": true, "dd": true, "dt": true, "figcaption": true, "hr": true,
+}
+
+// Elements without a closing tag
+var voidTags = map[string]bool{
+	"br": true, "hr": true, "img": true, "input": true, "meta": true, "link": true,
+	"area": true, "base": true, "col": true, "embed": true, "source": true, "wbr": true,
+}
+
+// textBlock is one paragraph of page text
+type textBlock struct {
+	text      string
+	linkRunes int  // Runes inside links
+	heading   bool // Inside h1 to h6
+	inArticle bool // Inside article or main
+}
+
+// Paragraphs shorter than this are menus, bylines and the like, unless
+// they are headings or end a sentence
+const minBlockRunes = 25
+
+// ExtractText returns the title and the main text of an HTML page, the way
+// reader views do: scripts, navigation, headers, footers and link lists are
+// dropped, and when the page marks its article only that part is kept.
+func ExtractText(page string) (title string, text string) {
+	blocks, title := scanBlocks(page)
+
+	article := false
+	for _, b := range blocks {
+		if b.inArticle {
+			article = true
+			break
+		}
+	}
+
+	var kept []string
+	for _, b := range blocks {
+		if article && !b.inArticle {
+			continue
+		}
+		runes := utf8.RuneCountInString(b.text)
+		if b.linkRunes*2 > runes {
+			continue // Mostly links: menus, tag clouds, related articles
+		}
+		if runes < minBlockRunes && !(b.heading && runes >= 2) && !endsSentence(b.text) {
+			continue
+		}
+		if len(kept) > 0 && kept[len(kept)-1] == b.text {
+			continue
+		}
+		kept = append(kept, b.text)
+	}
+	return title, strings.Join(kept, "\n\n")
+}
+
+// scanBlocks walks the page once, splitting its visible text into
+// paragraphs at block level tags
+func scanBlocks(page string) ([]textBlock, string) {
+	var blocks []textBlock
+	var title string
+	var current strings.Builder
+	var currentLinks int
+	var boilerplate, links, headings, articles int
+	lower := asciiLower(page) // Same offsets as page, for finding closing tags
+
+	flush := func() {
+		text := collapseSpace(current.String())
+		if text != "" {
+			blocks = append(blocks, textBlock{
+				text:      text,
+				linkRunes: min(currentLinks, utf8.RuneCountInString(text)),
+				heading:   headings > 0,
+				inArticle: articles > 0,
+			})
+		}
+		current.Reset()
+		currentLinks = 0
+	}
+
+	for i := 0; i < len(page); {
+		lt := strings.IndexByte(page[i:], '<')
+		if lt == -1 {
+			lt = len(page) - i
+		}
+		if lt > 0 {
+			if boilerplate == 0 {
+				text := html.UnescapeString(page[i : i+lt])
+				current.WriteString(text)
+				if links > 0 {
+					currentLinks += utf8.RuneCountInString(strings.TrimSpace(text))
+				}
+			}
+			i += lt
+			continue
+		}
+
+		// Comments and doctype
+		if strings.HasPrefix(page[i:], "<!--") {
+			end := strings.Index(page[i+4:], "-->")
+			if end == -1 {
+				break
+			}
+			i += 4 + end + 3
+			continue
+		}
+		gt := strings.IndexByte(page[i:], '>')
+		if gt == -1 {
+			break
+		}
+		name, closing := tagName(page[i+1 : i+gt])
+		i += gt + 1
+		if name == "" {
+			continue
+		}
+
+		if !closing && (skipTags[name] || name == "title") {
+			// Jump over the content, it may contain '<' that is not markup
+			end := strings.Index(lower[i:], "</"+name)
+			if end == -1 {
+				break
+			}
+			if name == "title" && title == "" {
+				title = collapseSpace(html.UnescapeString(page[i : i+end]))
+			}
+			i += end
+			continue
+		}
+
+		if blockTags[name] {
+			flush()
+		}
+		delta := 1
+		if closing {
+			delta = -1
+		} else if voidTags[name] {
+			continue
+		}
+		switch {
+		case boilerplateTags[name]:
+			boilerplate = max(0, boilerplate+delta)
+		case name == "a":
+			links = max(0, links+delta)
+		case name == "article" || name == "main":
+			articles = max(0, articles+delta)
+		case len(name) == 2 && name[0] == 'h' && name[1] >= '1' && name[1] <= '6':
+			headings = max(0, headings+delta)
+		}
+	}
+	flush()
+	return blocks, title
+}
+
+// tagName returns the lower case name of the tag inside < and >
+func tagName(tag string) (string, bool) {
+	closing := strings.HasPrefix(tag, "/")
+	tag = strings.TrimPrefix(tag, "/")
+	end := strings.IndexFunc(tag, func(r rune) bool {
+		return r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == '/'
+	})
+	if end != -1 {
+		tag = tag[:end]
+	}
+	if tag == "" || !isASCIILetter(tag[0]) {
+		return "", false // Doctype, processing instructions and stray '<'
+	}
+	return strings.ToLower(tag), closing
+}
+
+func isASCIILetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// asciiLower lowers ASCII letters only, so byte offsets stay the same
+func asciiLower(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		if c >= 'A' && c <= 'Z' {
+			b[i] = c + 'a' - 'A'
+		}
+	}
+	return string(b)
+}
+
+// collapseSpace joins the words of s with single spaces
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// endsSentence reports whether text ends like a sentence
+func endsSentence(text string) bool {
+	r, _ := utf8.DecodeLastRuneInString(text)
+	return strings.ContainsRune(".!?。！？", r)
+}
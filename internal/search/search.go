@@ -0,0 +1,161 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Search provider names accepted by New
+const (
+	DuckDuckGo = "duckduckgo" // Scrapes the DuckDuckGo HTML page, needs no key
+	SearXNG    = "searxng"    // Self-hosted SearXNG instance with the JSON format enabled
+	Brave      = "brave"      // Brave Search API
+	Bing       = "bing"       // Bing Web Search API
+)
+
+// DefaultLimit is how many results a search returns when no limit is given
+const DefaultLimit = 10
+
+// Result is one web page found by a search
+type Result struct {
+	Title   string `json:"title"`
+	URL     string `json:"url"`
+	Snippet string `json:"snippet"`
+}
+
+// Provider searches the web
+type Provider interface {
+	Name() string
+	// Search returns at most limit results for query, best first. A query
+	// without matches gives no results, not an error.
+	Search(ctx context.Context, query string, limit int) ([]Result, error)
+}
+
+// Config holds the settings of every provider, each uses its own fields
+type Config struct {
+	URL    string // SearXNG instance, or another endpoint for the other providers
+	APIKey string // Brave and Bing subscription key
+}
+
+// New returns the named provider. An empty name uses DuckDuckGo.
+func New(name string, cfg Config) (Provider, error) {
+	switch name {
+	case DuckDuckGo, "":
+		return &DuckDuckGoProvider{URL: cfg.URL}, nil
+	case SearXNG:
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("SearXNG URL not configured")
+		}
+		return &SearXNGProvider{URL: cfg.URL}, nil
+	case Brave:
+		if cfg.APIKey == "" {
+			return nil, fmt.Errorf("Brave Search API key not configured")
+		}
+		return &BraveProvider{URL: cfg.URL, APIKey: cfg.APIKey}, nil
+	case Bing:
+		if cfg.APIKey == "" {
+			return nil, fmt.Errorf("Bing Search API key not configured")
+		}
+		return &BingProvider{URL: cfg.URL, APIKey: cfg.APIKey}, nil
+	}
+	return nil, fmt.Errorf("unknown search provider: %s", name)
+}
+
+// Format lists the first MaxSources results as context for the model,
+// numbered with the IDs of Sources
+func Format(results []Result, language string) string {
+	if len(results) == 0 {
+		if language == "zh-TW" || language == "zh" {
+			return "未找到相關搜尋結果。"
+		}
+		return "No search results found."
+	}
+
+	var sb strings.Builder
+	for i, result := range results {
+		if i >= MaxSources {
+			break
+		}
+		sb.WriteString(fmt.Sprintf("[%d] %s\n", i+1, result.Title))
+		if result.Snippet != "" && result.Snippet != result.Title {
+			sb.WriteString(fmt.Sprintf("   %s\n", result.Snippet))
+		}
+		if result.URL != "" {
+			sb.WriteString(fmt.Sprintf("   來源: %s\n", result.URL))
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// newClient returns an HTTP client that uses the system proxy except for
+// servers on this machine, such as a local SearXNG
+func newClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			Proxy: func(req *http.Request) (*url.URL, error) {
+				host := req.URL.Hostname()
+				if host == "127.0.0.1" || host == "localhost" {
+					return nil, nil
+				}
+				return http.ProxyFromEnvironment(req)
+			},
+		},
+	}
+}
+
+// getJSON sends req and decodes the JSON answer into out
+func getJSON(req *http.Request, out interface{}) error {
+	req.Header.Set("Accept", "application/json")
+	resp, err := newClient(30 * time.Second).Do(req)
+	if err != nil {
+		return fmt.Errorf("http request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("API error (%d): %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
+
+// withQuery returns base with the query parameters added
+func withQuery(base string, params url.Values) (string, error) {
+	u, err := url.Parse(base)
+	if err != nil {
+		return "", fmt.Errorf("invalid search URL %q: %w", base, err)
+	}
+	query := u.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// clampLimit returns the result limit to use for limit
+func clampLimit(limit int) int {
+	if limit <= 0 {
+		return DefaultLimit
+	}
+	return limit
+}
+
+// truncate keeps at most limit results
+func truncate(results []Result, limit int) []Result {
+	if len(results) > limit {
+		return results[:limit]
+	}
+	return results
+}
@@ -0,0 +1,217 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// standIn serves body and records the request it received
+func standIn(t *testing.T, body string) (*httptest.Server, *http.Request) {
+	t.Helper()
+	var got http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = *r.Clone(context.Background())
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server, &got
+}
+
+func TestSearXNG(t *testing.T) {
+	server, got := standIn(t, `{"results":[
+		{"title":"Go","url":"https://go.dev","content":"The Go language"},
+		{"title":"Tour","url":"https://go.dev/tour","content":"A tour of Go"}]}`)
+
+	provider, err := New(SearXNG, Config{URL: server.URL + "/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := provider.Search(context.Background(), "golang 教學", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.URL.Path != "/search" || got.URL.Query().Get("q") != "golang 教學" || got.URL.Query().Get("format") != "json" {
+		t.Errorf("request = %s", got.URL)
+	}
+	if len(results) != 1 || results[0] != (Result{Title: "Go", URL: "https://go.dev", Snippet: "The Go language"}) {
+		t.Errorf("results = %+v, want the first result only", results)
+	}
+}
+
+func TestBrave(t *testing.T) {
+	server, got := standIn(t, `{"web":{"results":[{"title":"<strong>Go</strong>","url":"https://go.dev","description":"Build &amp; ship"}]}}`)
+
+	provider, err := New(Brave, Config{URL: server.URL, APIKey: "brave-key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := provider.Search(context.Background(), "go", 50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Header.Get("X-Subscription-Token") != "brave-key" || got.URL.Query().Get("count") != "20" {
+		t.Errorf("request = %s %v", got.URL, got.Header)
+	}
+	if len(results) != 1 || results[0].Title != "Go" || results[0].Snippet != "Build & ship" {
+		t.Errorf("results = %+v", results)
+	}
+}
+
+func TestBing(t *testing.T) {
+	server, got := standIn(t, `{"webPages":{"value":[{"name":"Go","url":"https://go.dev","snippet":"The Go language"}]}}`)
+
+	provider, err := New(Bing, Config{URL: server.URL, APIKey: "bing-key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := provider.Search(context.Background(), "go", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Header.Get("Ocp-Apim-Subscription-Key") != "bing-key" || got.URL.Query().Get("count") != "10" {
+		t.Errorf("request = %s %v", got.URL, got.Header)
+	}
+	if len(results) != 1 || results[0].URL != "https://go.dev" {
+		t.Errorf("results = %+v", results)
+	}
+}
+
+func TestAPIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid subscription token", http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	provider, _ := New(Brave, Config{URL: server.URL, APIKey: "wrong"})
+	_, err := provider.Search(context.Background(), "go", 5)
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("err = %v, want the status in the error", err)
+	}
+}
+
+const duckDuckGoPage = `<div class="result">
+<a rel="nofollow" class="result__a" href="//duckduckgo.com/l/?uddg=https%3A%2F%2Fgo.dev%2Fdoc%2F&amp;rut=abc">The <b>Go</b> docs</a>
+<a class="result__snippet" href="#">Documentation &amp; tutorials</a>
+</div>
+<div class="result">
+<a rel="nofollow" class="result__a" href="https://example.com/">Example</a>
+</div>`
+
+func TestDuckDuckGo(t *testing.T) {
+	server, got := standIn(t, duckDuckGoPage)
+
+	provider, _ := New("", Config{URL: server.URL})
+	results, err := provider.Search(context.Background(), "go docs", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.URL.Query().Get("q") != "go docs" {
+		t.Errorf("request = %s", got.URL)
+	}
+	want := []Result{
+		{Title: "The Go docs", URL: "https://go.dev/doc/", Snippet: "Documentation & tutorials"},
+		{Title: "Example", URL: "https://example.com/"},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("results = %+v, want %+v", results, want)
+	}
+	for i := range want {
+		if results[i] != want[i] {
+			t.Errorf("result %d = %+v, want %+v", i, results[i], want[i])
+		}
+	}
+}
+
+func TestDuckDuckGoChangedMarkup(t *testing.T) {
+	if results, err := parseDuckDuckGo(`<div class="no-results">No results.</div>`, 10); err != nil || len(results) != 0 {
+		t.Errorf("no results page gave %+v, %v", results, err)
+	}
+	if _, err := parseDuckDuckGo(`<html><form id="captcha"></form></html>`, 10); !errors.Is(err, errUnknownPage) {
+		t.Errorf("err = %v, want errUnknownPage for an unknown page", err)
+	}
+}
+
+func TestNewNeedsSettings(t *testing.T) {
+	for _, name := range []string{SearXNG, Brave, Bing, "yahoo"} {
+		if _, err := New(name, Config{}); err == nil {
+			t.Errorf("New(%s) without settings should fail", name)
+		}
+	}
+}
+
+func TestCite(t *testing.T) {
+	sources := Sources([]Result{
+		{Title: "One", URL: "https://one.example"},
+		{Title: "Two", URL: "https://two.example"},
+		{Title: "Three", URL: "https://three.example"},
+	})
+	// Brackets that are not all source IDs are ordinary text
+	answer, cited := Cite("台北今天下雨【3】，明天放晴 [1, 3]。颱風 [9] 仍在海上[2]，arr[10] 與 [2, 2024] 不變", sources)
+	if want := "台北今天下雨[3]，明天放晴 [1][3]。颱風 [9] 仍在海上[2]，arr[10] 與 [2, 2024] 不變"; answer != want {
+		t.Errorf("answer = %q, want %q", answer, want)
+	}
+	if len(cited) != 3 || cited[0].ID != 1 || cited[1].ID != 2 || cited[2].URL != "https://three.example" {
+		t.Errorf("cited = %+v, want sources 1 to 3 in order", cited)
+	}
+
+	if _, cited := Cite("沒有引用", sources[:2]); len(cited) != 2 {
+		t.Errorf("uncited answer gave %+v, want every source", cited)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	merged := Merge([][]Result{
+		{{Title: "A", URL: "https://a.example/"}, {Title: "B", URL: "https://b.example", Snippet: "short"}},
+		{{Title: "B", URL: "https://www.B.example#top", Snippet: "a longer snippet"}, {Title: "C", URL: "https://c.example"}},
+		{{Title: "D", URL: "https://d.example"}},
+	}, 3)
+
+	// B is found twice and comes first, then the single hits in order of discovery
+	want := []string{"B", "A", "D"}
+	if len(merged) != len(want) {
+		t.Fatalf("merged = %+v, want %v", merged, want)
+	}
+	for i, title := range want {
+		if merged[i].Title != title {
+			t.Errorf("merged[%d] = %s, want %s", i, merged[i].Title, title)
+		}
+	}
+	if merged[0].URL != "https://b.example" || merged[0].Snippet != "a longer snippet" {
+		t.Errorf("merged B = %+v, want the first URL and the longest snippet", merged[0])
+	}
+}
+
+// fakeProvider answers each query with its own results
+type fakeProvider map[string][]Result
+
+func (p fakeProvider) Name() string { return "fake" }
+
+func (p fakeProvider) Search(ctx context.Context, query string, limit int) ([]Result, error) {
+	results, ok := p[query]
+	if !ok {
+		return nil, errors.New("search failed")
+	}
+	return truncate(results, limit), nil
+}
+
+func TestSearchAll(t *testing.T) {
+	provider := fakeProvider{
+		"颱風 路徑":         {{Title: "氣象署", URL: "https://cwa.gov.tw"}},
+		"typhoon track": {{Title: "JTWC", URL: "https://jtwc.example"}, {Title: "氣象署", URL: "https://cwa.gov.tw/"}},
+	}
+	results, err := SearchAll(context.Background(), provider, []string{"颱風 路徑", "typhoon track", "broken"}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].Title != "氣象署" || results[1].Title != "JTWC" {
+		t.Errorf("results = %+v", results)
+	}
+
+	if _, err := SearchAll(context.Background(), provider, []string{"broken"}, 10); err == nil {
+		t.Error("SearchAll should fail when every query fails")
+	}
+}
@@ -0,0 +1,123 @@
+package table
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Table is a grid read from a screenshot
+type Table struct {
+	Title   string     `json:"title"`
+	Columns []string   `json:"columns"` // Header row, empty when the table has none
+	Rows    [][]string `json:"rows"`
+}
+
+// Width returns the number of columns of the widest row
+func (t Table) Width() int {
+	width := len(t.Columns)
+	for _, row := range t.Rows {
+		width = max(width, len(row))
+	}
+	return width
+}
+
+// Normalize trims every cell, pads short rows and the header to the same
+// width and drops empty rows
+func (t Table) Normalize() Table {
+	trim := func(cells []string) []string {
+		out := make([]string, len(cells))
+		for i, cell := range cells {
+			out[i] = strings.TrimSpace(cell)
+		}
+		return out
+	}
+	normalized := Table{Title: strings.TrimSpace(t.Title), Columns: trim(t.Columns)}
+	for _, row := range t.Rows {
+		row = trim(row)
+		if strings.Join(row, "") != "" {
+			normalized.Rows = append(normalized.Rows, row)
+		}
+	}
+
+	width := normalized.Width()
+	pad := func(cells []string) []string {
+		for len(cells) < width {
+			cells = append(cells, "")
+		}
+		return cells
+	}
+	if len(normalized.Columns) > 0 {
+		normalized.Columns = pad(normalized.Columns)
+	}
+	for i, row := range normalized.Rows {
+		normalized.Rows[i] = pad(row)
+	}
+	if normalized.Rows == nil {
+		normalized.Rows = [][]string{}
+	}
+	return normalized
+}
+
+// Records returns the header followed by the rows
+func (t Table) Records() [][]string {
+	records := make([][]string, 0, len(t.Rows)+1)
+	if len(t.Columns) > 0 {
+		records = append(records, t.Columns)
+	}
+	return append(records, t.Rows...)
+}
+
+// Markdown formats the table for the history and the chat window
+func (t Table) Markdown() string {
+	var b strings.Builder
+	if t.Title != "" {
+		b.WriteString("**" + t.Title + "**\n\n")
+	}
+	width := t.Width()
+	if width == 0 {
+		return b.String()
+	}
+
+	writeRow := func(cells []string) {
+		b.WriteString("|")
+		for i := 0; i < width; i++ {
+			cell := ""
+			if i < len(cells) {
+				cell = strings.ReplaceAll(cells[i], "|", "\\|")
+				cell = strings.ReplaceAll(cell, "\n", " ")
+			}
+			b.WriteString(" " + cell + " |")
+		}
+		b.WriteString("\n")
+	}
+	header := t.Columns
+	if len(header) == 0 {
+		// Markdown tables need a header, use an empty one
+		header = make([]string, width)
+	}
+	writeRow(header)
+	b.WriteString("|" + strings.Repeat(" --- |", width) + "\n")
+	for _, row := range t.Rows {
+		writeRow(row)
+	}
+	return b.String()
+}
+
+// Number parses a cell that holds a plain number, such as "1,234.5" or
+// "-12", so exports can keep it numeric. Codes with leading zeros such as
+// "007" stay text.
+func Number(cell string) (float64, bool) {
+	cell = strings.ReplaceAll(strings.TrimSpace(cell), ",", "")
+	digits := strings.TrimPrefix(cell, "-")
+	if digits == "" || digits[0] < '0' || digits[0] > '9' {
+		return 0, false // Also keeps ParseFloat's "NaN" and "Inf" out
+	}
+	if len(digits) > 1 && digits[0] == '0' && digits[1] != '.' {
+		return 0, false
+	}
+	value, err := strconv.ParseFloat(cell, 64)
+	if err != nil {
+		return 0, false
+	}
+	return value, true
+}
@@ -0,0 +1,106 @@
+package table
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func sample() Table {
+	return Table{
+		Title:   "訂單",
+		Columns: []string{"品項", "數量", "金額"},
+		Rows: [][]string{
+			{"紅茶", "2", "1,200"},
+			{"蛋糕, 巧克力", "1"},
+			{"", " ", ""},
+		},
+	}.Normalize()
+}
+
+func TestNormalize(t *testing.T) {
+	table := sample()
+	if len(table.Rows) != 2 {
+		t.Fatalf("rows = %q, want the empty row dropped", table.Rows)
+	}
+	if len(table.Rows[1]) != 3 || table.Rows[1][2] != "" {
+		t.Errorf("short row = %q, want it padded to 3 cells", table.Rows[1])
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	want := "**訂單**\n\n| 品項 | 數量 | 金額 |\n| --- | --- | --- |\n| 紅茶 | 2 | 1,200 |\n| 蛋糕, 巧克力 | 1 |  |\n"
+	if got := sample().Markdown(); got != want {
+		t.Errorf("Markdown() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, sample(), FormatCSV); err != nil {
+		t.Fatal(err)
+	}
+	want := "\ufeff品項,數量,金額\n紅茶,2,\"1,200\"\n\"蛋糕, 巧克力\",1,\n"
+	if buf.String() != want {
+		t.Errorf("csv = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestWriteXLSX(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, sample(), FormatXLSX); err != nil {
+		t.Fatal(err)
+	}
+	reader, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("not a zip file: %v", err)
+	}
+
+	files := make(map[string]string)
+	for _, file := range reader.File {
+		rc, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(rc)
+		rc.Close()
+		files[file.Name] = string(body)
+	}
+	for _, name := range []string{"[Content_Types].xml", "xl/workbook.xml", "xl/worksheets/sheet1.xml"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("missing %s", name)
+		}
+	}
+
+	sheet := files["xl/worksheets/sheet1.xml"]
+	for _, want := range []string{
+		`<c r="A1" t="inlineStr" s="1"><is><t xml:space="preserve">品項</t></is></c>`,
+		`<c r="C2"><v>1200</v></c>`,
+		`<t xml:space="preserve">蛋糕, 巧克力</t>`,
+	} {
+		if !strings.Contains(sheet, want) {
+			t.Errorf("sheet is missing %s:\n%s", want, sheet)
+		}
+	}
+	if !strings.Contains(files["xl/workbook.xml"], `name="訂單"`) {
+		t.Errorf("workbook = %s, want the title as sheet name", files["xl/workbook.xml"])
+	}
+}
+
+func TestColumnName(t *testing.T) {
+	for index, want := range map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 701: "ZZ", 702: "AAA"} {
+		if got := columnName(index); got != want {
+			t.Errorf("columnName(%d) = %s, want %s", index, got, want)
+		}
+	}
+}
+
+func TestNumber(t *testing.T) {
+	for cell, want := range map[string]bool{"1,200": true, "-3.5": true, "0.25": true, "0": true, "007": false, "12%": false, "": false, "abc": false, "NaN": false, "0x1F": false} {
+		if _, ok := Number(cell); ok != want {
+			t.Errorf("Number(%q) ok = %v, want %v", cell, ok, want)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package tokens
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DefaultContextWindow is assumed for models missing from the table
+const DefaultContextWindow = 8192
+
+// minOutputTokens is the smallest answer budget worth sending a request for
+const minOutputTokens = 256
+
+// safetyMargin covers the chat template tokens the estimate does not see
+const safetyMargin = 64
+
+// contextWindows maps model name prefixes to their context length in
+// tokens. More specific prefixes come first.
+var contextWindows = []struct {
+	prefix string
+	tokens int
+}{
+	{"gpt-4.1", 1047576},
+	{"gpt-4o", 128000},
+	{"gpt-4-turbo", 128000},
+	{"gpt-4", 8192},
+	{"gpt-3.5", 16385},
+	{"gpt-5", 400000},
+	{"gpt-oss", 131072},
+	{"o1", 200000},
+	{"o3", 200000},
+	{"o4", 200000},
+	{"claude", 200000},
+	{"gemini-1.5", 1048576},
+	{"gemini-2", 1048576},
+	{"gemini", 32768},
+	{"qwen3", 32768},
+	{"qwen2.5", 32768},
+	{"deepseek", 65536},
+	{"llama3.1", 131072},
+	{"llama3.2", 131072},
+	{"llama3", 8192},
+	{"mistral", 32768},
+	{"gemma3", 131072},
+	{"gemma", 8192},
+}
+
+// ContextWindow returns the context length of model in tokens. Names such
+// as "openai/gpt-oss-20b" or "qwen3-vl:4b" are matched on the part after
+// the last slash.
+func ContextWindow(model string) int {
+	name := strings.ToLower(strings.TrimSpace(model))
+	if i := strings.LastIndex(name, "/"); i != -1 {
+		name = name[i+1:]
+	}
+	for _, entry := range contextWindows {
+		if strings.HasPrefix(name, entry.prefix) {
+			return entry.tokens
+		}
+	}
+	return DefaultContextWindow
+}
+
+// Estimate approximates how many tokens text takes. Each CJK character
+// counts as one token, other words as one token per five characters and
+// punctuation as one token each. It errs on the high side for English.
+func Estimate(text string) int {
+	count := 0
+	word := 0
+	flush := func() {
+		if word > 0 {
+			count += (word + 4) / 5
+			word = 0
+		}
+	}
+	for _, r := range text {
+		switch {
+		case isCJK(r):
+			flush()
+			count++
+		case unicode.IsSpace(r):
+			flush()
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if r < utf8.RuneSelf {
+				word++
+			} else {
+				word += 2 // Accented and non-Latin letters split into more pieces
+			}
+		default:
+			flush()
+			count++
+		}
+	}
+	flush()
+	return count
+}
+
+// OutputBudget returns how many tokens the answer may use when the prompt
+// takes promptTokens of model's context. requested is the configured limit,
+// 0 means as much as fits.
+func OutputBudget(model string, promptTokens int, requested int) int {
+	available := ContextWindow(model) - promptTokens - safetyMargin
+	if requested <= 0 || requested > available {
+		requested = available
+	}
+	if requested < minOutputTokens {
+		requested = minOutputTokens
+	}
+	return requested
+}
+
+// Truncate cuts text to at most maxTokens without splitting a character
+func Truncate(text string, maxTokens int) string {
+	if maxTokens <= 0 {
+		return ""
+	}
+	if Estimate(text) <= maxTokens {
+		return text
+	}
+
+	// Binary search on the number of runes that still fits
+	runes := []rune(text)
+	lo, hi := 0, len(runes)
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if Estimate(string(runes[:mid])) <= maxTokens {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	return string(runes[:lo])
+}
+
+// Split breaks text into chunks of at most maxTokens, preferring line
+// breaks and never splitting a character
+func Split(text string, maxTokens int) []string {
+	if maxTokens <= 0 || text == "" {
+		return nil
+	}
+
+	var chunks []string
+	var current strings.Builder
+	currentTokens := 0
+	flush := func() {
+		if strings.TrimSpace(current.String()) != "" {
+			chunks = append(chunks, current.String())
+		}
+		current.Reset()
+		currentTokens = 0
+	}
+
+	for _, line := range strings.SplitAfter(text, "\n") {
+		lineTokens := Estimate(line)
+		if currentTokens+lineTokens > maxTokens {
+			flush()
+		}
+		// A single line longer than a chunk is cut into pieces
+		for lineTokens > maxTokens {
+			piece := Truncate(line, maxTokens)
+			if piece == "" {
+				// Guarantee progress on a single oversized character
+				_, size := utf8.DecodeRuneInString(line)
+				piece = line[:size]
+			}
+			chunks = append(chunks, piece)
+			line = line[len(piece):]
+			lineTokens = Estimate(line)
+		}
+		current.WriteString(line)
+		currentTokens += lineTokens
+	}
+	flush()
+	return chunks
+}
+
+// isCJK reports whether r is a Chinese, Japanese or Korean character
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) ||
+		unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) ||
+		unicode.Is(unicode.Hangul, r) ||
+		(r >= 0x3000 && r <= 0x303F) || // CJK punctuation
+		(r >= 0xFF00 && r <= 0xFFEF) // Full-width forms
+}
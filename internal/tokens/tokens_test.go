@@ -0,0 +1,88 @@
+package tokens
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestEstimate(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected int
+	}{
+		{name: "Empty", input: "", expected: 0},
+		{name: "English words", input: "hello world", expected: 2},
+		{name: "Long word", input: "internationalization", expected: 4},
+		{name: "Chinese", input: "你好世界", expected: 4},
+		{name: "Punctuation", input: "Hi, there!", expected: 4},
+		{name: "Mixed", input: "Go 語言", expected: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Estimate(tt.input); got != tt.expected {
+				t.Errorf("Estimate(%q) = %d, want %d", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestContextWindow(t *testing.T) {
+	tests := map[string]int{
+		"gpt-4o-mini":        128000,
+		"gpt-4":              8192,
+		"openai/gpt-oss-20b": 131072,
+		"qwen3-vl:4b":        32768,
+		"claude-sonnet-4-5":  200000,
+		"something-else":     DefaultContextWindow,
+	}
+	for model, want := range tests {
+		if got := ContextWindow(model); got != want {
+			t.Errorf("ContextWindow(%q) = %d, want %d", model, got, want)
+		}
+	}
+}
+
+func TestOutputBudget(t *testing.T) {
+	if got := OutputBudget("gpt-4", 1000, 2048); got != 2048 {
+		t.Errorf("fits: got %d", got)
+	}
+	if got := OutputBudget("gpt-4", 7000, 2048); got != 8192-7000-safetyMargin {
+		t.Errorf("clamped: got %d", got)
+	}
+	if got := OutputBudget("gpt-4", 9000, 2048); got != minOutputTokens {
+		t.Errorf("overflow: got %d", got)
+	}
+}
+
+func TestTruncateKeepsRunesWhole(t *testing.T) {
+	text := strings.Repeat("中文測試", 100)
+	out := Truncate(text, 10)
+	if !utf8.ValidString(out) {
+		t.Fatalf("Truncate split a character: %q", out)
+	}
+	if Estimate(out) != 10 {
+		t.Errorf("Estimate(out) = %d, want 10", Estimate(out))
+	}
+}
+
+func TestSplit(t *testing.T) {
+	text := strings.Repeat("第一行的內容。\n", 50) + strings.Repeat("很長", 100)
+	chunks := Split(text, 40)
+	if len(chunks) < 2 {
+		t.Fatalf("expected several chunks, got %d", len(chunks))
+	}
+	if strings.Join(chunks, "") != text {
+		t.Errorf("chunks do not add up to the original text")
+	}
+	for i, chunk := range chunks {
+		if Estimate(chunk) > 40 {
+			t.Errorf("chunk %d has %d tokens", i, Estimate(chunk))
+		}
+		if !utf8.ValidString(chunk) {
+			t.Errorf("chunk %d is not valid UTF-8", i)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/Kelen/Korner/internal/metrics"
+)
+
+// openMetrics opens the usage store, requests are not recorded without it
+func (a *App) openMetrics() {
+	store, err := metrics.Open(metrics.DefaultDir())
+	if err != nil {
+		log.Printf("Warning: failed to open usage metrics: %v", err)
+		return
+	}
+	a.metrics = store
+}
+
+// GetUsageStats summarizes requests, latency and tokens of the range
+// "day", "week", "month" or "all" for the usage dashboard
+func (a *App) GetUsageStats(rangeName string) (*metrics.Summary, error) {
+	if a.metrics == nil {
+		return nil, fmt.Errorf("usage metrics not initialized")
+	}
+	summary, err := a.metrics.Summary(rangeName)
+	if err != nil {
+		return nil, fmt.Errorf("load usage metrics: %w", err)
+	}
+	return summary, nil
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/Kelen/Korner/internal/cache"
+	"github.com/Kelen/Korner/internal/metrics"
+	"github.com/Kelen/Korner/internal/ocr"
+)
+
+// ocrConfig collects the settings of every OCR engine
+func (s *AppSettings) ocrConfig() ocr.EngineConfig {
+	endpoint := s.OllamaEndpoint
+	if endpoint == "" {
+		endpoint = ocr.DefaultEndpoint
+	}
+	return ocr.EngineConfig{
+		OllamaEndpoint: endpoint,
+		OllamaModel:    s.ollamaModel(),
+		TesseractPath:  s.TesseractPath,
+		Languages:      s.TesseractLanguages,
+		ServerURL:      s.OCRServerURL,
+	}
+}
+
+// ocrEngine returns the engine chosen in settings. Unless tesseract is the
+// choice, an installed tesseract takes over when the engine fails, so
+// screenshots are still read while Ollama is down.
+func (a *App) ocrEngine() (ocr.Engine, error) {
+	cfg := a.settings.ocrConfig()
+	engine, err := ocr.NewEngine(a.settings.OCREngine, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if engine.Name() != ocr.EngineTesseract {
+		tesseract := &ocr.TesseractEngine{Path: cfg.TesseractPath, Languages: cfg.Languages}
+		if tesseract.Available() {
+			engine = ocr.WithFallback(engine, tesseract)
+		}
+	}
+	return engine, nil
+}
+
+// ocrModel names what reads the text for an engine, used in cache keys and metrics
+func ocrModel(engine string, cfg ocr.EngineConfig) string {
+	switch engine {
+	case ocr.EngineTesseract:
+		if cfg.Languages == "" {
+			return ocr.DefaultTesseractLanguages
+		}
+		return cfg.Languages
+	case ocr.EngineHTTP:
+		return cfg.ServerURL
+	}
+	return cfg.OllamaEndpoint + " " + cfg.OllamaModel
+}
+
+// recognize reads the screenshot with the configured engine
+func (a *App) recognize(ctx context.Context, screenshotBase64 string) (*ocr.Result, error) {
+	engine, err := a.ocrEngine()
+	if err != nil {
+		return nil, err
+	}
+	return a.recognizeWith(ctx, engine, screenshotBase64)
+}
+
+// recognizeWith reads the screenshot with engine. Results are cached by the
+// hash of the image and the engine settings.
+func (a *App) recognizeWith(ctx context.Context, engine ocr.Engine, screenshotBase64 string) (*ocr.Result, error) {
+	cfg := a.settings.ocrConfig()
+	log.Printf("[OCR] Extracting text with %s (base64 length: %d)", engine.Name(), len(screenshotBase64))
+
+	// The same screenshot read by the same engine gives the same text
+	key := cache.Key("ocr", engine.Name(), ocrModel(engine.Name(), cfg), cache.HashBase64(screenshotBase64))
+	if !cache.Bypassed(ctx) {
+		var cached ocr.Result
+		if a.responseCache().Get(key, &cached) {
+			log.Printf("[OCR] Using cached text (length: %d)", len(cached.Text))
+			return &cached, nil
+		}
+	}
+
+	start := time.Now()
+	result, err := engine.Recognize(ctx, screenshotBase64)
+	name := engine.Name()
+	if result != nil {
+		name = result.Engine
+	}
+	a.metrics.Observe(metrics.KindOCR, name, ocrModel(name, cfg), start, err)
+	if err != nil {
+		log.Printf("[OCR] Failed to extract text: %v", err)
+		return nil, fmt.Errorf("OCR failed: %w", err)
+	}
+
+	log.Printf("[OCR] %s extracted text (length: %d, blocks: %d)", result.Engine, len(result.Text), len(result.Blocks))
+	// Answers from a fallback engine are not cached under the primary's key
+	if strings.TrimSpace(result.Text) != "" && result.Engine == engine.Name() {
+		if err := a.responseCache().Put(key, result); err != nil {
+			log.Printf("[OCR] Warning: failed to cache text: %v", err)
+		}
+	}
+	return result, nil
+}
+
+// GetScreenshotLayout reads the screenshot and returns where each block,
+// line and word sits in it, so the overlay can draw selectable text over
+// the image. Vision models give no positions, so tesseract reads the
+// layout instead when it is installed.
+func (a *App) GetScreenshotLayout(screenshotBase64 string) (*ocr.Result, error) {
+	ctx := a.baseContext()
+	engine, err := a.ocrEngine()
+	if err != nil {
+		return nil, err
+	}
+	result, err := a.recognizeWith(ctx, engine, screenshotBase64)
+	if err == nil && hasPositions(result) {
+		return result, nil
+	}
+
+	tesseract := &ocr.TesseractEngine{Path: a.settings.TesseractPath, Languages: a.settings.TesseractLanguages}
+	if engine.Name() == ocr.EngineTesseract || !tesseract.Available() {
+		return result, err
+	}
+	log.Printf("[OCR] %s gave no positions, reading the layout with tesseract", engine.Name())
+	if layout, layoutErr := a.recognizeWith(ctx, tesseract, screenshotBase64); layoutErr == nil {
+		return layout, nil
+	} else if err == nil {
+		log.Printf("[OCR] Warning: tesseract failed, returning text without positions: %v", layoutErr)
+	}
+	return result, err
+}
+
+// hasPositions reports whether any line of result has a box
+func hasPositions(result *ocr.Result) bool {
+	for _, block := range result.Blocks {
+		if block.Box != nil {
+			return true
+		}
+	}
+	return false
+}
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"context"
 	"fmt"
 	"log"
 	"os"
@@ -13,7 +12,9 @@ import (
 	"github.com/Kelen/Korner/internal/audio"
 	"github.com/Kelen/Korner/internal/document"
 	"github.com/Kelen/Korner/internal/history"
+	"github.com/Kelen/Korner/internal/llm"
 	"github.com/Kelen/Korner/internal/meeting"
+	"github.com/Kelen/Korner/internal/metrics"
 	"github.com/Kelen/Korner/internal/ocr"
 	wailsruntime "github.com/wailsapp/wails/v2/pkg/runtime"
 )
@@ -102,17 +103,23 @@ func (a *App) SelectAudioFile() (string, error) {
 	return filePath, nil
 }
 
-// GenerateMeetingSummary transcribes audio and generates a meeting summary
-func (a *App) GenerateMeetingSummary(audioPath string) (string, error) {
-	ctx := a.ctx
-	if ctx == nil {
-		ctx = context.Background()
-	}
+// MeetingSummaryResult is a meeting summary as text and, when the model
+// returned valid JSON, as structured notes
+type MeetingSummaryResult struct {
+	Text  string         `json:"text"`
+	Notes *meeting.Notes `json:"notes,omitempty"`
+}
+
+// GenerateMeetingSummary transcribes audio and generates a meeting summary.
+// CancelRequest(requestID) stops the transcription or the summary.
+func (a *App) GenerateMeetingSummary(requestID string, audioPath string) (*MeetingSummaryResult, error) {
+	ctx, done := a.beginRequest(requestID)
+	defer done()
 
 	// 1. 轉錄音訊
 	generator, err := meeting.NewGenerator()
 	if err != nil {
-		return "", fmt.Errorf("failed to initialize meeting generator: %w\n\n請確保已安裝 Python 和 Whisper:\npip install openai-whisper", err)
+		return nil, fmt.Errorf("failed to initialize meeting generator: %w\n\n請確保已安裝 Python 和 Whisper:\npip install openai-whisper", err)
 	}
 
 	language := a.settings.Language
@@ -120,23 +127,46 @@ func (a *App) GenerateMeetingSummary(audioPath string) (string, error) {
 		language = "zh-TW"
 	}
 
+	start := time.Now()
 	result, err := generator.Generate(ctx, audioPath, language)
+	a.metrics.Observe(metrics.KindTranscribe, "whisper", "tiny", start, err)
 	if err != nil {
-		return "", err
+		return nil, requestError(ctx, err)
 	}
 
-	// 2. 使用 Ollama 生成會議摘要（不需要聯網）
-	summaryPrompt := meeting.GenerateSummaryPrompt(language, result.Transcription)
-
-	ollamaEndpoint := a.settings.OllamaEndpoint
-	if ollamaEndpoint == "" {
-		ollamaEndpoint = "http://127.0.0.1:11434"
-	}
-
-	// 使用 QueryOllama 而非 QueryOllamaWithWebSearch，因為摘要不需要聯網
-	summary, err := ocr.QueryOllama(ctx, summaryPrompt, "", ollamaEndpoint, language)
+	// 2. 使用 Ollama 生成結構化會議摘要（不需要聯網）
+	summary := &MeetingSummaryResult{}
+	provider, err := llm.NewProvider("ollama", a.providerConfig("ollama"))
 	if err != nil {
-		return "", fmt.Errorf("failed to generate summary: %w", err)
+		return nil, err
+	}
+	provider = llm.WithMetrics(provider, a.metrics)
+	var notes meeting.Notes
+	_, err = llm.QueryJSON(ctx, provider, llm.Request{
+		Query:    meeting.NotesPrompt(language, result.Transcription),
+		Language: language,
+	}, meeting.NotesSchema(), &notes)
+	if ctx.Err() != nil {
+		return nil, requestError(ctx, err)
+	}
+	if err == nil {
+		summary.Notes = &notes
+		summary.Text = notes.Format(language)
+	} else {
+		// 模型無法產生有效的 JSON 時改用純文字摘要
+		log.Printf("[MeetingSummary] Warning: structured summary failed, using plain text: %v", err)
+
+		ollamaEndpoint := a.settings.OllamaEndpoint
+		if ollamaEndpoint == "" {
+			ollamaEndpoint = ocr.DefaultEndpoint
+		}
+		summaryPrompt := meeting.GenerateSummaryPrompt(language, result.Transcription)
+		start := time.Now()
+		summary.Text, err = ocr.QueryOllama(ctx, summaryPrompt, "", ollamaEndpoint, a.settings.ollamaModel(), language)
+		a.metrics.Observe(metrics.KindLLM, "ollama", a.settings.ollamaModel(), start, err)
+		if err != nil {
+			return nil, requestError(ctx, fmt.Errorf("failed to generate summary: %w", err))
+		}
 	}
 
 	log.Printf("[MeetingSummary] Summary generated successfully")
@@ -146,7 +176,7 @@ func (a *App) GenerateMeetingSummary(audioPath string) (string, error) {
 		conv := history.Conversation{
 			Timestamp:      time.Now(),
 			Question:       "會議摘要 - " + filepath.Base(audioPath),
-			Answer:         summary,
+			Answer:         summary.Text,
 			ScreenshotPath: audioPath,
 			Provider:       a.settings.APIProvider,
 			Model:          "whisper-tiny + ollama",
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+)
+
+// ErrRequestCancelled is returned when the user cancels an in-flight request
+var ErrRequestCancelled = errors.New("請求已取消")
+
+// baseContext returns the app-lifetime context, or Background before startup
+func (a *App) baseContext() context.Context {
+	if a.ctx != nil {
+		return a.ctx
+	}
+	return context.Background()
+}
+
+// activeRequest is one registered request. Entries are compared by
+// pointer, so a finished request never removes a newer one with its ID.
+type activeRequest struct {
+	cancel context.CancelFunc
+}
+
+// beginRequest derives a cancellable context for one request and registers
+// it under requestID so CancelRequest can stop it. The returned func must be
+// called when the request finishes. An empty requestID is not registered.
+func (a *App) beginRequest(requestID string) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(a.baseContext())
+	if requestID == "" {
+		return ctx, cancel
+	}
+
+	entry := &activeRequest{cancel: cancel}
+	a.requestsMu.Lock()
+	if a.requests == nil {
+		a.requests = make(map[string]*activeRequest)
+	}
+	if previous, ok := a.requests[requestID]; ok {
+		// A reused ID replaces the older request
+		previous.cancel()
+	}
+	a.requests[requestID] = entry
+	a.requestsMu.Unlock()
+
+	return ctx, func() {
+		a.requestsMu.Lock()
+		if a.requests[requestID] == entry {
+			delete(a.requests, requestID)
+		}
+		a.requestsMu.Unlock()
+		cancel()
+	}
+}
+
+// CancelRequest stops the in-flight request registered under requestID.
+// It returns false when no such request is running.
+func (a *App) CancelRequest(requestID string) bool {
+	a.requestsMu.Lock()
+	entry, ok := a.requests[requestID]
+	delete(a.requests, requestID)
+	a.requestsMu.Unlock()
+
+	if !ok {
+		return false
+	}
+	log.Printf("[Request] Cancelling request %s", requestID)
+	entry.cancel()
+	return true
+}
+
+// requestError reports ErrRequestCancelled when ctx was cancelled, so the
+// frontend can tell a stopped request from a failed one
+func requestError(ctx context.Context, err error) error {
+	if errors.Is(ctx.Err(), context.Canceled) {
+		return ErrRequestCancelled
+	}
+	return err
+}
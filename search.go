@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/Kelen/Korner/internal/history"
+	"github.com/Kelen/Korner/internal/llm"
+	"github.com/Kelen/Korner/internal/prompts"
+	"github.com/Kelen/Korner/internal/search"
+	"github.com/Kelen/Korner/internal/tokens"
+)
+
+// maxSearchQueries is how many queries planQueries runs for one question
+const maxSearchQueries = 3
+
+// fallbackQueryTokens caps the question when it is searched as is
+const fallbackQueryTokens = 64
+
+// maxSearchContextTokens caps the search results and page text sent to
+// models with very long context windows, more rarely improves the answer
+const maxSearchContextTokens = 12000
+
+// searchProvider returns the web search provider chosen in settings, or
+// DuckDuckGo when that one is not fully configured
+func (a *App) searchProvider() search.Provider {
+	provider, err := search.New(a.settings.SearchProvider, search.Config{
+		URL:    a.settings.SearchURL,
+		APIKey: a.settings.SearchAPIKey,
+	})
+	if err != nil {
+		log.Printf("[Search] %v, using DuckDuckGo", err)
+		provider, _ = search.New(search.DuckDuckGo, search.Config{})
+	}
+	return provider
+}
+
+// searchQueries is what the model answers to the search_queries prompt
+type searchQueries struct {
+	Queries []string `json:"queries"`
+}
+
+// planQueries asks p for up to three short search queries covering the
+// question and the screenshot text. When planning fails the question itself
+// is searched, so a slow or confused model never blocks the search.
+func planQueries(ctx context.Context, p llm.Provider, question string, ocrText string, language string) []string {
+	fallback := strings.Join(strings.Fields(question), " ")
+	if fallback == "" {
+		fallback = strings.Join(strings.Fields(ocrText), " ")
+	}
+	fallback = tokens.Truncate(fallback, fallbackQueryTokens)
+
+	data := prompts.NewData(language)
+	data.Query = question
+	data.OCRText = ocrText
+	req := llm.Request{Query: prompts.Render(prompts.SearchQueries, data), Language: language}
+
+	var planned searchQueries
+	if _, err := llm.QueryJSON(ctx, p, req, searchQueriesSchema(), &planned); err != nil {
+		log.Printf("[Search] Query planning with %s failed, searching the question: %v", p.Name(), err)
+		return []string{fallback}
+	}
+
+	seen := make(map[string]bool)
+	queries := make([]string, 0, maxSearchQueries)
+	for _, query := range planned.Queries {
+		query = strings.Join(strings.Fields(query), " ")
+		key := strings.ToLower(query)
+		if query == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		queries = append(queries, query)
+		if len(queries) == maxSearchQueries {
+			break
+		}
+	}
+	if len(queries) == 0 {
+		return []string{fallback}
+	}
+	log.Printf("[Search] Planned queries: %q", queries)
+	return queries
+}
+
+// searchQueriesSchema returns the JSON Schema of searchQueries for llm.QueryJSON
+func searchQueriesSchema() llm.JSONSchema {
+	return llm.JSONSchema{
+		Name: "search_queries",
+		Schema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"queries": map[string]interface{}{
+					"type":  "array",
+					"items": map[string]interface{}{"type": "string"},
+				},
+			},
+			"required":             []string{"queries"},
+			"additionalProperties": false,
+		},
+	}
+}
+
+// WebSearchAnswer is an answer built from web search results. Answer marks
+// claims with [n], the ID of the backing entry in Sources.
+type WebSearchAnswer struct {
+	Answer         string          `json:"answer"`
+	Sources        []search.Source `json:"sources"`
+	ConversationID string          `json:"conversationId,omitempty"`
+}
+
+// QueryLLMWithWebSearch answers a query from web search results with the
+// configured provider, moving on to the fallbacks when it is down. The
+// primary provider plans the searches, the top pages are read and sized to
+// the context window of whichever provider answers. The request can be
+// stopped with CancelRequest(requestID).
+func (a *App) QueryLLMWithWebSearch(requestID string, query string, screenshotBase64 string, language string) (*WebSearchAnswer, error) {
+	if a.settings == nil {
+		return nil, fmt.Errorf("Settings not initialized. Please configure your API settings.")
+	}
+	ctx, done := a.beginRequest(requestID)
+	defer done()
+
+	if language == "" {
+		language = a.settings.Language
+	}
+	if language == "" {
+		language = "zh-TW"
+	}
+
+	chain := a.providerChain()
+	log.Printf("[QueryLLMWithWebSearch] Starting query with provider: %s (%d in chain)", chain[0].Name(), len(chain))
+	log.Printf("[QueryLLMWithWebSearch] Query: %s", query)
+
+	// Look up the screenshot before the query, capture_screen saves newer ones
+	screenshotPath := ""
+	if screenshotBase64 != "" {
+		screenshotPath, _ = getLastScreenshotPath()
+	}
+
+	// The search needs the screenshot as text, whatever the provider can see
+	question := query
+	var ocrText string
+	if screenshotBase64 != "" {
+		log.Printf("[QueryLLMWithWebSearch] Screenshot provided, extracting text...")
+		text, err := a.extractText(ctx, screenshotBase64)
+		if ctx.Err() != nil {
+			return nil, requestError(ctx, err)
+		}
+		if err != nil {
+			log.Printf("[QueryLLMWithWebSearch] Warning: OCR failed: %v", err)
+		} else if text != "" {
+			ocrText = text
+			query = query + "\n\n[圖片中的文字內容]\n" + text
+		}
+	}
+
+	// Retrieval: planned queries, merged results and the text of the top pages
+	queries := planQueries(ctx, llm.WithMetrics(chain[0], a.metrics), question, ocrText, language)
+	results, err := search.SearchAll(ctx, a.searchProvider(), queries, search.DefaultLimit)
+	if ctx.Err() != nil {
+		return nil, requestError(ctx, err)
+	}
+	if err != nil {
+		log.Printf("[QueryLLMWithWebSearch] Warning: web search failed: %v", err)
+	}
+	pages := search.FetchPages(ctx, results, search.FetchOptions{})
+	if ctx.Err() != nil {
+		return nil, requestError(ctx, ctx.Err())
+	}
+
+	// Generation: each provider gets the context sized to its own window
+	bases := make(map[string]llm.Provider, len(chain))
+	for i, provider := range chain {
+		bases[provider.Name()] = provider
+		chain[i] = a.wrapProvider(provider, language)
+	}
+	build := func(provider llm.Provider) (llm.Request, error) {
+		budget := min(llm.ContextWindow(bases[provider.Name()])/2, maxSearchContextTokens)
+		data := prompts.NewData(language)
+		data.SearchResults = search.BuildContext(results, pages, budget, language)
+		data.Query = query
+		return llm.Request{Query: prompts.Render(prompts.WebSearch, data), Language: language}, nil
+	}
+	resp, provider, err := llm.QueryWithFallback(ctx, chain, llm.DefaultRetryPolicy, build)
+	if err != nil {
+		log.Printf("[QueryLLMWithWebSearch] ERROR: %v", err)
+		return nil, requestError(ctx, err)
+	}
+
+	answer := &WebSearchAnswer{}
+	answer.Answer, answer.Sources = search.Cite(resp.Text, search.Sources(results))
+	log.Printf("[QueryLLMWithWebSearch] Success from %s! Response length: %d, %d sources", provider.Name(), len(answer.Answer), len(answer.Sources))
+
+	// Save to history
+	if a.history != nil {
+		conv := history.Conversation{
+			ID:             history.NewID(),
+			Timestamp:      time.Now(),
+			Question:       query + " [聯網搜尋]",
+			Answer:         answer.Answer,
+			ScreenshotPath: screenshotPath,
+			Provider:       provider.Name(),
+			Model:          resp.Model,
+			Reasoning:      resp.Reasoning,
+			ToolCalls:      historyToolCalls(resp.ToolCalls),
+			Sources:        historySources(answer.Sources),
+		}
+		if err := a.history.Save(conv); err != nil {
+			log.Printf("Warning: failed to save conversation to history: %v", err)
+		} else {
+			answer.ConversationID = conv.ID
+		}
+	}
+
+	return answer, nil
+}
+
+// historySources converts cited sources for the history record
+func historySources(sources []search.Source) []history.Source {
+	out := make([]history.Source, len(sources))
+	for i, source := range sources {
+		out[i] = history.Source(source)
+	}
+	return out
+}
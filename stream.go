@@ -0,0 +1,55 @@
+package main
+
+import (
+	"log"
+
+	wailsruntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// StreamEvent is emitted on the "llm-stream" event while an answer is generated
+type StreamEvent struct {
+	RequestID string          `json:"requestId"`
+	Chunk     string          `json:"chunk,omitempty"`
+	Text      string          `json:"text,omitempty"`      // Final cleaned answer, set when Done
+	Reasoning string          `json:"reasoning,omitempty"` // Model reasoning split from the answer, set when Done
+	Condensed *CondenseNotice `json:"condensed,omitempty"` // Sent before the answer when the input was too long
+
+	Done  bool   `json:"done"`
+	Error string `json:"error,omitempty"`
+}
+
+// QueryLLMStream works like QueryLLM but emits partial chunks to the frontend
+// as "llm-stream" events tagged with requestID. The final cleaned answer is
+// returned and sent in a last event with Done set. Queries sharing a
+// threadID are answered with the earlier turns of the thread as context.
+// The request can be stopped with CancelRequest(requestID), and noCache
+// asks the model again instead of reusing a cached answer.
+func (a *App) QueryLLMStream(requestID string, threadID string, query string, screenshotBase64 string, language string, noCache bool) (string, error) {
+	log.Printf("[QueryLLMStream] Request %s started (thread: %s)", requestID, threadID)
+
+	ctx, done := a.beginRequest(requestID)
+	defer done()
+	ctx = withCacheBypass(ctx, noCache)
+
+	resp, err := a.queryLLM(ctx, threadID, query, screenshotBase64, language, func(chunk string) {
+		a.emitStream(StreamEvent{RequestID: requestID, Chunk: chunk})
+	}, func(notice CondenseNotice) {
+		a.emitStream(StreamEvent{RequestID: requestID, Condensed: &notice})
+	})
+	if err != nil {
+		a.emitStream(StreamEvent{RequestID: requestID, Done: true, Error: err.Error()})
+		return "", err
+	}
+
+	a.emitStream(StreamEvent{RequestID: requestID, Text: resp.Text, Reasoning: resp.Reasoning, Done: true})
+	log.Printf("[QueryLLMStream] Request %s finished", requestID)
+	return resp.Text, nil
+}
+
+// emitStream sends a stream event to the frontend
+func (a *App) emitStream(event StreamEvent) {
+	if a.ctx == nil {
+		return
+	}
+	wailsruntime.EventsEmit(a.ctx, "llm-stream", event)
+}
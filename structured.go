@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/Kelen/Korner/internal/llm"
+)
+
+// QueryLLMJSON asks the configured provider for an answer matching the JSON
+// Schema and returns it decoded, see llm.QueryJSON. name identifies the
+// schema to OpenAI-compatible servers. Providers without vision get the
+// screenshot as OCR text. The request can be stopped with
+// CancelRequest(requestID).
+func (a *App) QueryLLMJSON(requestID string, query string, screenshotBase64 string, language string, name string, schema map[string]interface{}) (interface{}, error) {
+	if a.settings == nil {
+		return nil, fmt.Errorf("Settings not initialized. Please configure your API settings.")
+	}
+	if len(schema) == 0 {
+		return nil, fmt.Errorf("JSON schema is empty")
+	}
+	ctx, done := a.beginRequest(requestID)
+	defer done()
+
+	if language == "" {
+		language = a.settings.Language
+	}
+	if name == "" {
+		name = "answer"
+	}
+
+	provider := llm.WithMetrics(a.providerChain()[0], a.metrics)
+	req := llm.Request{Query: query, Language: language}
+	if screenshotBase64 != "" {
+		if provider.Capabilities().Vision {
+			req.ImageBase64 = screenshotBase64
+		} else {
+			text, err := a.extractText(ctx, screenshotBase64)
+			if ctx.Err() != nil {
+				return nil, requestError(ctx, err)
+			}
+			if err != nil {
+				log.Printf("[QueryLLMJSON] Warning: OCR failed, continuing without extracted text: %v", err)
+			} else if text != "" {
+				if query != "" {
+					req.Query = query + "\n\n[圖片中的文字內容]\n" + text
+				} else {
+					req.Query = "[圖片中的文字內容]\n" + text
+				}
+			}
+		}
+	}
+
+	log.Printf("[QueryLLMJSON] Asking %s for %s JSON", provider.Name(), name)
+	var out interface{}
+	if _, err := llm.QueryJSON(ctx, provider, req, llm.JSONSchema{Name: name, Schema: schema}, &out); err != nil {
+		return nil, requestError(ctx, err)
+	}
+	return out, nil
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Kelen/Korner/internal/history"
+	"github.com/Kelen/Korner/internal/llm"
+	"github.com/Kelen/Korner/internal/ocr"
+	"github.com/Kelen/Korner/internal/prompts"
+	"github.com/Kelen/Korner/internal/table"
+	wailsruntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// TableResult is a table read from a screenshot
+type TableResult struct {
+	Table          table.Table `json:"table"`
+	Source         string      `json:"source"` // OCR engine or provider that read the table
+	ConversationID string      `json:"conversationId,omitempty"`
+}
+
+// ExtractTable reads the main table in the screenshot as a grid and saves
+// it with a history entry. A table found by the OCR layout is used as is,
+// otherwise the configured provider is asked for it as JSON. The request
+// can be stopped with CancelRequest(requestID).
+func (a *App) ExtractTable(requestID string, screenshotBase64 string, language string) (*TableResult, error) {
+	if a.settings == nil {
+		return nil, fmt.Errorf("Settings not initialized. Please configure your API settings.")
+	}
+	ctx, done := a.beginRequest(requestID)
+	defer done()
+
+	if language == "" {
+		language = a.settings.Language
+	}
+	if language == "" {
+		language = "zh-TW"
+	}
+
+	layout, ocrErr := a.recognize(ctx, screenshotBase64)
+	if ctx.Err() != nil {
+		return nil, requestError(ctx, ocrErr)
+	}
+
+	result := &TableResult{}
+	var provider, model string
+	if ocrErr == nil && len(layout.Tables) > 0 {
+		result.Table = tableFromLayout(layout.Tables)
+		result.Source = layout.Engine
+		provider = layout.Engine
+		log.Printf("[Table] Using the table found by %s", layout.Engine)
+	} else {
+		if ocrErr != nil {
+			log.Printf("[Table] Warning: OCR failed: %v", ocrErr)
+		}
+		chain := a.providerChain()
+		primary := llm.WithMetrics(chain[0], a.metrics)
+
+		data := prompts.NewData(language)
+		req := llm.Request{Language: language}
+		if primary.Capabilities().Vision {
+			req.ImageBase64 = screenshotBase64
+		} else if ocrErr != nil {
+			return nil, fmt.Errorf("OCR failed: %w", ocrErr)
+		} else {
+			data.OCRText = layout.Text
+		}
+		req.Query = prompts.Render(prompts.Table, data)
+
+		log.Printf("[Table] Asking %s for the table", primary.Name())
+		resp, err := llm.QueryJSON(ctx, primary, req, tableSchema(), &result.Table)
+		if err != nil {
+			return nil, requestError(ctx, fmt.Errorf("failed to extract table: %w", err))
+		}
+		result.Source = primary.Name()
+		provider, model = primary.Name(), resp.Model
+	}
+
+	result.Table = result.Table.Normalize()
+	if len(result.Table.Rows) == 0 {
+		return nil, errors.New("no table found in the screenshot")
+	}
+	log.Printf("[Table] Extracted %d rows x %d columns", len(result.Table.Rows), result.Table.Width())
+
+	if a.history != nil {
+		screenshotPath, _ := getLastScreenshotPath()
+		grid := history.Table(result.Table)
+		conv := history.Conversation{
+			ID:             history.NewID(),
+			Timestamp:      time.Now(),
+			Question:       "📊 擷取表格",
+			Answer:         result.Table.Markdown(),
+			ScreenshotPath: screenshotPath,
+			Provider:       provider,
+			Model:          model,
+			Table:          &grid,
+		}
+		if err := a.history.Save(conv); err != nil {
+			log.Printf("Warning: failed to save table to history: %v", err)
+		} else {
+			result.ConversationID = conv.ID
+		}
+	}
+	return result, nil
+}
+
+// ExportTable asks where to save the table and writes it as "csv" or
+// "xlsx". It returns the saved path, or "" when the user cancels.
+func (a *App) ExportTable(grid table.Table, format string) (string, error) {
+	filters := map[string]wailsruntime.FileFilter{
+		table.FormatCSV:  {DisplayName: "CSV (*.csv)", Pattern: "*.csv"},
+		table.FormatXLSX: {DisplayName: "Excel (*.xlsx)", Pattern: "*.xlsx"},
+	}
+	filter, ok := filters[format]
+	if !ok {
+		return "", fmt.Errorf("unknown table format: %s", format)
+	}
+
+	name := strings.TrimSpace(grid.Title)
+	if name == "" {
+		name = "table-" + time.Now().Format("20060102-150405")
+	}
+	path, err := wailsruntime.SaveFileDialog(a.ctx, wailsruntime.SaveDialogOptions{
+		Title:           "匯出表格",
+		DefaultFilename: name + "." + format,
+		Filters:         []wailsruntime.FileFilter{filter},
+	})
+	if err != nil {
+		return "", fmt.Errorf("save dialog: %w", err)
+	}
+	if path == "" {
+		return "", nil
+	}
+	if !strings.HasSuffix(strings.ToLower(path), "."+format) {
+		path += "." + format
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("create %s: %w", path, err)
+	}
+	if err := table.Write(file, grid, format); err != nil {
+		file.Close()
+		return "", err
+	}
+	if err := file.Close(); err != nil {
+		return "", fmt.Errorf("write %s: %w", path, err)
+	}
+	log.Printf("[Table] Exported %d rows to %s", len(grid.Rows), path)
+	return path, nil
+}
+
+// tableFromLayout converts the largest table the OCR engine found, taking
+// its first row as the header
+func tableFromLayout(tables []ocr.Table) table.Table {
+	largest := tables[0]
+	for _, t := range tables[1:] {
+		if cellCount(t) > cellCount(largest) {
+			largest = t
+		}
+	}
+
+	rows := make([][]string, len(largest.Rows))
+	for i, row := range largest.Rows {
+		rows[i] = make([]string, len(row))
+		for j, cell := range row {
+			rows[i][j] = cell.Text
+		}
+	}
+	return table.Table{Columns: rows[0], Rows: rows[1:]}
+}
+
+func cellCount(t ocr.Table) int {
+	n := 0
+	for _, row := range t.Rows {
+		n += len(row)
+	}
+	return n
+}
+
+// tableSchema returns the JSON Schema of table.Table for llm.QueryJSON
+func tableSchema() llm.JSONSchema {
+	str := map[string]interface{}{"type": "string"}
+	strList := map[string]interface{}{"type": "array", "items": str}
+	properties := map[string]interface{}{
+		"title":   str,
+		"columns": strList,
+		"rows":    map[string]interface{}{"type": "array", "items": strList},
+	}
+	required := make([]string, 0, len(properties))
+	for name := range properties {
+		required = append(required, name)
+	}
+	sort.Strings(required)
+
+	return llm.JSONSchema{
+		Name: "table",
+		Schema: map[string]interface{}{
+			"type":                 "object",
+			"properties":           properties,
+			"required":             required,
+			"additionalProperties": false,
+		},
+	}
+}
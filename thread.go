@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/Kelen/Korner/internal/history"
+	"github.com/Kelen/Korner/internal/llm"
+)
+
+// threadHistory loads the earlier turns of a thread, trimmed to budget
+// tokens. Screenshots are only replayed to vision providers.
+func (a *App) threadHistory(threadID string, withImages bool, budget int) []llm.Turn {
+	if threadID == "" || a.history == nil {
+		return nil
+	}
+
+	conversations, err := a.history.GetThread(threadID)
+	if err != nil {
+		log.Printf("[Thread] Warning: failed to load thread %s: %v", threadID, err)
+		return nil
+	}
+
+	turns := make([]llm.Turn, 0, len(conversations))
+	for _, conv := range conversations {
+		turn := llm.Turn{
+			Question: conv.Question,
+			Answer:   conv.Answer,
+		}
+		if withImages && conv.ScreenshotPath != "" {
+			if data, err := os.ReadFile(conv.ScreenshotPath); err == nil {
+				turn.ImageBase64 = base64.StdEncoding.EncodeToString(data)
+			} else {
+				log.Printf("[Thread] Warning: screenshot not available: %v", err)
+			}
+		}
+		turns = append(turns, turn)
+	}
+
+	trimmed := llm.TrimHistory(turns, budget)
+	if len(trimmed) < len(turns) {
+		log.Printf("[Thread] Trimmed thread %s from %d to %d turns", threadID, len(turns), len(trimmed))
+	}
+	return trimmed
+}
+
+// historyBudget returns how many tokens earlier turns may use with p,
+// leaving most of a small context window to the new question
+func historyBudget(p llm.Provider, maxTokens int) int {
+	budget := llm.InputBudget(p, maxTokens) / 3
+	if budget > llm.DefaultHistoryBudget {
+		budget = llm.DefaultHistoryBudget
+	}
+	return budget
+}
+
+// GetThreadHistory returns all conversations of a thread, oldest first
+func (a *App) GetThreadHistory(threadID string) ([]history.Conversation, error) {
+	if a.history == nil {
+		return nil, fmt.Errorf("history manager not initialized")
+	}
+	return a.history.GetThread(threadID)
+}
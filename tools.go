@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/Kelen/Korner/internal/document"
+	"github.com/Kelen/Korner/internal/history"
+	"github.com/Kelen/Korner/internal/llm"
+	"github.com/Kelen/Korner/internal/search"
+)
+
+// assistantTools returns the tools the model may call while answering
+func (a *App) assistantTools(language string) []llm.Tool {
+	return []llm.Tool{
+		{
+			Name:        "web_search",
+			Description: "Search the web for current information. Returns titles, URLs and snippets of the top results.",
+			Parameters: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"query": map[string]interface{}{"type": "string", "description": "Search keywords"},
+				},
+				"required": []string{"query"},
+			},
+			Run: func(ctx context.Context, args json.RawMessage) (string, error) {
+				var in struct {
+					Query string `json:"query"`
+				}
+				if err := json.Unmarshal(args, &in); err != nil || strings.TrimSpace(in.Query) == "" {
+					return "", fmt.Errorf("web_search needs a query")
+				}
+				results, err := a.searchProvider().Search(ctx, in.Query, search.DefaultLimit)
+				if err != nil {
+					return "", err
+				}
+				return search.Format(results, language), nil
+			},
+		},
+		{
+			Name:        "read_pdf",
+			Description: "Extract the text of a local PDF file given its full path.",
+			Parameters: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{"type": "string", "description": "Absolute path of the PDF file"},
+				},
+				"required": []string{"path"},
+			},
+			Run: func(ctx context.Context, args json.RawMessage) (string, error) {
+				var in struct {
+					Path string `json:"path"`
+				}
+				if err := json.Unmarshal(args, &in); err != nil || in.Path == "" {
+					return "", fmt.Errorf("read_pdf needs a path")
+				}
+				if strings.ToLower(filepath.Ext(in.Path)) != ".pdf" {
+					return "", fmt.Errorf("only .pdf files can be read")
+				}
+				return document.ExtractPDFText(in.Path)
+			},
+		},
+		{
+			Name:        "search_history",
+			Description: "Search the user's earlier questions and answers in Korner.",
+			Parameters: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"query": map[string]interface{}{"type": "string", "description": "Text to look for"},
+					"limit": map[string]interface{}{"type": "integer", "description": "Maximum number of results, default 5"},
+				},
+				"required": []string{"query"},
+			},
+			Run: func(ctx context.Context, args json.RawMessage) (string, error) {
+				var in struct {
+					Query string `json:"query"`
+					Limit int    `json:"limit"`
+				}
+				if err := json.Unmarshal(args, &in); err != nil {
+					return "", fmt.Errorf("invalid arguments: %w", err)
+				}
+				if a.history == nil {
+					return "", fmt.Errorf("history is not available")
+				}
+				if in.Limit <= 0 || in.Limit > 20 {
+					in.Limit = 5
+				}
+				matches, err := a.history.Search(in.Query, in.Limit)
+				if err != nil {
+					return "", err
+				}
+				return formatHistoryForLLM(matches), nil
+			},
+		},
+		{
+			Name:        "capture_screen",
+			Description: "Take a screenshot of the whole screen and return the text visible on it.",
+			Parameters: map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{},
+			},
+			Run: func(ctx context.Context, args json.RawMessage) (string, error) {
+				width, height := a.platform.GetScreenSize()
+				screenshot, err := a.platform.CaptureScreenshot(ctx, 0, 0, width, height)
+				if err != nil {
+					return "", fmt.Errorf("capture screen: %w", err)
+				}
+				text, err := a.extractText(ctx, screenshot)
+				if err != nil {
+					return "", err
+				}
+				if text == "" {
+					return "No text found on screen.", nil
+				}
+				return text, nil
+			},
+		},
+	}
+}
+
+// formatHistoryForLLM renders history matches as plain text for the model
+func formatHistoryForLLM(conversations []history.Conversation) string {
+	if len(conversations) == 0 {
+		return "No matching conversations."
+	}
+
+	var sb strings.Builder
+	for i, conv := range conversations {
+		answer := []rune(conv.Answer)
+		if len(answer) > 500 {
+			answer = append(answer[:500], []rune("...")...)
+		}
+		fmt.Fprintf(&sb, "%d. [%s]\nQ: %s\nA: %s\n\n", i+1, conv.Timestamp.Format("2006-01-02 15:04"), conv.Question, string(answer))
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// historyToolCalls converts tool calls for the conversation record
+func historyToolCalls(calls []llm.ToolCall) []history.ToolCall {
+	if len(calls) == 0 {
+		return nil
+	}
+	out := make([]history.ToolCall, 0, len(calls))
+	for _, call := range calls {
+		// Keep the record small, the full result was only needed by the model
+		result := []rune(call.Result)
+		if len(result) > 1000 {
+			result = append(result[:1000], []rune("...")...)
+		}
+		out = append(out, history.ToolCall{
+			Name:      call.Name,
+			Arguments: call.Arguments,
+			Result:    string(result),
+			Error:     call.Error,
+		})
+	}
+	return out
+}